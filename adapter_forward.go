@@ -0,0 +1,604 @@
+package log
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultForwardTag the default tag of fluent forward protocol.
+const DefaultForwardTag = "app"
+
+// ForwardConfig fluentd/fluent-bit forward 适配器配置
+// see https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+type ForwardConfig struct {
+	// Network 网络类型, tcp,unix 默认tcp
+	Network string `yaml:"network" json:"network"`
+	// Address 地址, 默认 localhost:24224
+	Address string `yaml:"address" json:"address"`
+	// Tag 标签前缀, 日志标签为 <tag>.<logger name>, 默认app
+	Tag string `yaml:"tag" json:"tag"`
+	// BatchSize 每批最大日志条数, 使用 PackedForward 模式发送, 默认1000
+	BatchSize int `yaml:"batchSize" json:"batchSize"`
+	// BatchBytes 每批最大字节数, 默认1MB
+	BatchBytes int `yaml:"batchBytes" json:"batchBytes"`
+	// FlushInterval 最大发送间隔, 默认1s
+	FlushInterval time.Duration `yaml:"flushInterval" json:"flushInterval"`
+	// QueueSize 队列大小, 队列满时日志将丢弃, 默认10000
+	QueueSize int `yaml:"queueSize" json:"queueSize"`
+	// RequireAck 是否要求服务端确认(chunk/ack), 默认false
+	RequireAck bool `yaml:"requireAck" json:"requireAck"`
+	// AckTimeout 确认超时, 默认10s
+	AckTimeout time.Duration `yaml:"ackTimeout" json:"ackTimeout"`
+	// DialTimeout 连接超时, 默认5s
+	DialTimeout time.Duration `yaml:"dialTimeout" json:"dialTimeout"`
+	// WriteTimeout 写超时, 默认5s
+	WriteTimeout time.Duration `yaml:"writeTimeout" json:"writeTimeout"`
+	// MaxRetries 发送失败时最大重试次数, 默认5
+	MaxRetries int `yaml:"maxRetries" json:"maxRetries"`
+	// MinBackoff 重连最小退避时间, 默认100ms, 每次失败加倍
+	MinBackoff time.Duration `yaml:"minBackoff" json:"minBackoff"`
+	// MaxBackoff 重连最大退避时间, 默认10s
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff"`
+}
+
+// forwardEncoder encode the entry as the msgpack [EventTime, record] of the forward protocol,
+// level, message, logger name, caller and stacktrace are added into the record with the keys of EncoderConfig.
+type forwardEncoder struct {
+	*zapcore.MapObjectEncoder
+	cfg *zapcore.EncoderConfig
+	ns  []string // the namespaces opened
+}
+
+// NewForwardEncoder creates a fluent forward encoder.
+func NewForwardEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &forwardEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              &cfg,
+	}
+}
+
+func (enc *forwardEncoder) OpenNamespace(key string) {
+	enc.MapObjectEncoder.OpenNamespace(key)
+	enc.ns = append(enc.ns[:len(enc.ns):len(enc.ns)], key)
+}
+
+func (enc *forwardEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.MapObjectEncoder = cloneMapEncoder(enc.MapObjectEncoder, enc.ns)
+	return &clone
+}
+
+func (enc *forwardEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.Clone().(*forwardEncoder)
+	for i := range fields {
+		fields[i].AddTo(final.MapObjectEncoder)
+	}
+	record := final.Fields
+	if enc.cfg.LevelKey != "" {
+		record[enc.cfg.LevelKey] = ent.Level.String()
+	}
+	if enc.cfg.MessageKey != "" {
+		record[enc.cfg.MessageKey] = ent.Message
+	}
+	if ent.LoggerName != "" && enc.cfg.NameKey != "" {
+		record[enc.cfg.NameKey] = ent.LoggerName
+	}
+	if ent.Caller.Defined && enc.cfg.CallerKey != "" {
+		record[enc.cfg.CallerKey] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" && enc.cfg.StacktraceKey != "" {
+		record[enc.cfg.StacktraceKey] = ent.Stack
+	}
+
+	buf := logfmtBufferPool.Get()
+	b := appendMsgpackArrayHeader(buf.Bytes(), 2)
+	b = appendMsgpackEventTime(b, ent.Time)
+	b = appendMsgpackValue(b, record)
+	buf.Reset()
+	_, _ = buf.Write(b)
+	return buf, nil
+}
+
+// forwardEntry the encoded [EventTime, record] wait for sending.
+type forwardEntry struct {
+	tag  string
+	data []byte
+	line []byte // the entry encoded with Format for the Fallback
+}
+
+// forwarder batch the entries by tag, and send them with PackedForward mode:
+//
+//	[tag, bin(<[EventTime, record]>...), {"size": n, "chunk": "<id>"}]
+type forwarder struct {
+	fc      ForwardConfig
+	batcher *batcher[forwardEntry]
+	conn    net.Conn
+	r       *bufio.Reader
+	backoff *backoff
+	dropped atomic.Int64
+	metrics Metrics
+	rescue  func(p []byte, err error) error
+}
+
+func newForwarder(fc ForwardConfig, m Metrics, rescue func(p []byte, err error) error) *forwarder {
+	fc.Network = strings.ToLower(fc.Network)
+	if fc.Network == "" {
+		fc.Network = "tcp"
+	}
+	if fc.Address == "" {
+		fc.Address = "localhost:24224"
+	}
+	if fc.Tag == "" {
+		fc.Tag = DefaultForwardTag
+	}
+	if fc.BatchSize <= 0 {
+		fc.BatchSize = 1000
+	}
+	if fc.BatchBytes <= 0 {
+		fc.BatchBytes = 1 << 20
+	}
+	if fc.FlushInterval <= 0 {
+		fc.FlushInterval = time.Second
+	}
+	if fc.QueueSize <= 0 {
+		fc.QueueSize = 10000
+	}
+	if fc.AckTimeout <= 0 {
+		fc.AckTimeout = 10 * time.Second
+	}
+	if fc.DialTimeout <= 0 {
+		fc.DialTimeout = 5 * time.Second
+	}
+	if fc.WriteTimeout <= 0 {
+		fc.WriteTimeout = 5 * time.Second
+	}
+	if fc.MaxRetries <= 0 {
+		fc.MaxRetries = 5
+	}
+	if fc.MinBackoff <= 0 {
+		fc.MinBackoff = 100 * time.Millisecond
+	}
+	if fc.MaxBackoff < fc.MinBackoff {
+		fc.MaxBackoff = 10 * time.Second
+	}
+	f := &forwarder{
+		fc:      fc,
+		backoff: newBackoff(fc.MinBackoff, fc.MaxBackoff),
+		metrics: m,
+		rescue:  rescue,
+	}
+	f.batcher = newBatcher(fc.QueueSize, fc.BatchSize, fc.BatchBytes, fc.FlushInterval,
+		func(e forwardEntry) int { return len(e.data) }, f.sendBatch)
+	return f
+}
+
+// tag returns the tag of the logger name.
+func (f *forwarder) tag(loggerName string) string {
+	if loggerName == "" {
+		return f.fc.Tag
+	}
+	return f.fc.Tag + "." + loggerName
+}
+
+// push the entry into the queue, the entry is dropped when the queue is full.
+func (f *forwarder) push(e forwardEntry) {
+	if !f.batcher.push(e) {
+		f.dropped.Add(1)
+		f.metrics.Dropped(DropQueueFull, 1)
+	}
+}
+
+// Sync send all the entries in the queue, and wait for them sent.
+func (f *forwarder) Sync() error { return f.batcher.Sync() }
+
+// Close send all the entries in the queue, and stop the forwarder.
+func (f *forwarder) Close() error {
+	err := f.batcher.Close()
+	if f.conn != nil {
+		err = errors.Join(err, f.conn.Close())
+		f.conn = nil
+	}
+	return err
+}
+
+// sendBatch send the entries grouped by tag in order of arrival.
+func (f *forwarder) sendBatch(batch []forwardEntry) {
+	var (
+		tags    []string
+		entries = make(map[string][]byte)
+		lines   = make(map[string][]byte)
+		counts  = make(map[string]int)
+	)
+	for _, e := range batch {
+		if _, ok := entries[e.tag]; !ok {
+			tags = append(tags, e.tag)
+		}
+		entries[e.tag] = append(entries[e.tag], e.data...)
+		lines[e.tag] = append(lines[e.tag], e.line...)
+		counts[e.tag]++
+	}
+	for _, tag := range tags {
+		f.send(tag, entries[tag], lines[tag], counts[tag])
+	}
+}
+
+// send the PackedForward message with retry and reconnect, the lines are
+// written to the Fallback if the retries exhausted.
+func (f *forwarder) send(tag string, entries, lines []byte, count int) {
+	var chunk string
+	if f.fc.RequireAck {
+		var id [16]byte
+		_, _ = rand.Read(id[:])
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+	}
+	msg := appendMsgpackArrayHeader(nil, 3)
+	msg = appendMsgpackString(msg, tag)
+	msg = appendMsgpackBinary(msg, entries)
+	if chunk != "" {
+		msg = appendMsgpackMapHeader(msg, 2)
+		msg = appendMsgpackString(msg, "chunk")
+		msg = appendMsgpackString(msg, chunk)
+	} else {
+		msg = appendMsgpackMapHeader(msg, 1)
+	}
+	msg = appendMsgpackString(msg, "size")
+	msg = appendMsgpackInt(msg, int64(count))
+
+	var err error
+	start := time.Now()
+	for i := 0; i <= f.fc.MaxRetries; i++ {
+		if err = f.write(msg, chunk); err == nil {
+			f.backoff.Reset()
+			f.metrics.Written(SinkForward, len(msg), time.Since(start))
+			return
+		}
+		f.metrics.SinkError(SinkForward)
+		if f.conn != nil {
+			_ = f.conn.Close()
+			f.conn = nil
+		}
+		if i < f.fc.MaxRetries {
+			f.batcher.sleep(f.backoff.Next())
+		}
+	}
+	if f.rescue != nil && f.rescue(lines, err) == nil {
+		return
+	}
+	f.dropped.Add(int64(count))
+	f.metrics.Dropped(DropSendFailed, count)
+}
+
+func (f *forwarder) write(msg []byte, chunk string) error {
+	if f.conn == nil {
+		conn, err := net.DialTimeout(f.fc.Network, f.fc.Address, f.fc.DialTimeout)
+		if err != nil {
+			return err
+		}
+		f.conn, f.r = conn, bufio.NewReader(conn)
+	}
+	if err := f.conn.SetWriteDeadline(time.Now().Add(f.fc.WriteTimeout)); err != nil {
+		return err
+	}
+	if _, err := f.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+	if err := f.conn.SetReadDeadline(time.Now().Add(f.fc.AckTimeout)); err != nil {
+		return err
+	}
+	resp, err := readMsgpackStringMap(f.r)
+	if err != nil {
+		return err
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("log: forward ack mismatch, want %s, got %s", chunk, resp["ack"])
+	}
+	return nil
+}
+
+// forwardCore the core which encode the entry and push it to the forwarder,
+// the tag is derived from the logger name.
+type forwardCore struct {
+	zapcore.LevelEnabler
+	enc       zapcore.Encoder
+	text      zapcore.Encoder // encode the entry for the Fallback, nil if not configured
+	forwarder *forwarder
+}
+
+func newForwardCore(enc, text zapcore.Encoder, enab zapcore.LevelEnabler, f *forwarder) zapcore.Core {
+	return &forwardCore{LevelEnabler: enab, enc: enc, text: text, forwarder: f}
+}
+
+func (c *forwardCore) With(fields []Field) zapcore.Core {
+	clone := &forwardCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		forwarder:    c.forwarder,
+	}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	if c.text != nil {
+		clone.text = c.text.Clone()
+		for i := range fields {
+			fields[i].AddTo(clone.text)
+		}
+	}
+	return clone
+}
+
+func (c *forwardCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *forwardCore) Write(ent zapcore.Entry, fields []Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	e := forwardEntry{
+		tag:  c.forwarder.tag(ent.LoggerName),
+		data: append([]byte(nil), buf.Bytes()...),
+	}
+	buf.Free()
+	if c.text != nil {
+		if buf, err = c.text.EncodeEntry(ent, fields); err == nil {
+			e.line = append([]byte(nil), buf.Bytes()...)
+			buf.Free()
+		}
+	}
+	c.forwarder.push(e)
+	if ent.Level > zapcore.ErrorLevel {
+		// the process may exit, such as panic and fatal.
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *forwardCore) Sync() error { return c.forwarder.Sync() }
+
+/******************************** msgpack **************************************/
+
+func appendMsgpackValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case string:
+		return appendMsgpackString(b, v)
+	case []byte:
+		return appendMsgpackBinary(b, v)
+	case int:
+		return appendMsgpackInt(b, int64(v))
+	case int64:
+		return appendMsgpackInt(b, v)
+	case int32:
+		return appendMsgpackInt(b, int64(v))
+	case int16:
+		return appendMsgpackInt(b, int64(v))
+	case int8:
+		return appendMsgpackInt(b, int64(v))
+	case uint:
+		return appendMsgpackUint(b, uint64(v))
+	case uint64:
+		return appendMsgpackUint(b, v)
+	case uint32:
+		return appendMsgpackUint(b, uint64(v))
+	case uint16:
+		return appendMsgpackUint(b, uint64(v))
+	case uint8:
+		return appendMsgpackUint(b, uint64(v))
+	case uintptr:
+		return appendMsgpackUint(b, uint64(v))
+	case float64:
+		return appendMsgpackFloat(b, v)
+	case float32:
+		return appendMsgpackFloat(b, float64(v))
+	case time.Time:
+		return appendMsgpackString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendMsgpackString(b, v.String())
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendMsgpackMapHeader(b, len(keys))
+		for _, k := range keys {
+			b = appendMsgpackString(b, k)
+			b = appendMsgpackValue(b, v[k])
+		}
+		return b
+	case []any:
+		b = appendMsgpackArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendMsgpackValue(b, e)
+		}
+		return b
+	case error:
+		return appendMsgpackString(b, v.Error())
+	default:
+		return appendMsgpackString(b, fmt.Sprint(v))
+	}
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendMsgpackUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func appendMsgpackUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+	}
+}
+
+func appendMsgpackFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackBinary(b []byte, p []byte) []byte {
+	switch n := len(p); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, p...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendMsgpackEventTime append the EventTime, which is the ext type 0
+// with 4-byte seconds and 4-byte nanoseconds.
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// readMsgpackStringMap read the map with string keys and values, such as the ack response.
+func readMsgpackStringMap(r *bufio.Reader) (map[string]string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case c&0xf0 == 0x80:
+		n = int(c & 0x0f)
+	case c == 0xde:
+		var size [2]byte
+		if _, err = io.ReadFull(r, size[:]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(size[:]))
+	default:
+		return nil, errors.New("log: msgpack map expected")
+	}
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func readMsgpackString(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == 0xd9:
+		size, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		n = int(size)
+	case c == 0xda:
+		var size [2]byte
+		if _, err = io.ReadFull(r, size[:]); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint16(size[:]))
+	default:
+		return "", errors.New("log: msgpack string expected")
+	}
+	s := make([]byte, n)
+	if _, err = io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
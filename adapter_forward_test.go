@@ -0,0 +1,317 @@
+package log_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+// msgpackDecode decode the msgpack value which is used by the forward protocol.
+func msgpackDecode(r *bufio.Reader) (any, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	readN := func(n int) ([]byte, error) {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	readLen := func(size int) (int, error) {
+		b, err := readN(size)
+		if err != nil {
+			return 0, err
+		}
+		switch size {
+		case 1:
+			return int(b[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(b)), nil
+		default:
+			return int(binary.BigEndian.Uint32(b)), nil
+		}
+	}
+	decodeArray := func(n int) (any, error) {
+		a := make([]any, n)
+		for i := range a {
+			if a[i], err = msgpackDecode(r); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	decodeMap := func(n int) (any, error) {
+		m := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k, err := msgpackDecode(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[fmt.Sprint(k)], err = msgpackDecode(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		b, err := readN(int(c & 0x1f))
+		return string(b), err
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readLen(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readN(n)
+	case 0xcb:
+		b, err := readN(8)
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := readN(1 << (c - 0xcc))
+		var v uint64
+		for _, x := range b {
+			v = v<<8 | uint64(x)
+		}
+		return int64(v), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		b, err := readN(1 << (c - 0xd0))
+		v := int64(int8(b[0]))
+		for _, x := range b[1:] {
+			v = v<<8 | int64(x)
+		}
+		return v, err
+	case 0xd7: // fixext8, EventTime
+		b, err := readN(9)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), int64(binary.BigEndian.Uint32(b[5:]))), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := readLen(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		b, err := readN(n)
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := readLen(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := readLen(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMap(n)
+	}
+	return nil, fmt.Errorf("unsupported msgpack type: %#x", c)
+}
+
+func Test_ForwardEncoder_Namespace(t *testing.T) {
+	decode := func(enc zapcore.Encoder, fields ...log.Field) map[string]any {
+		t.Helper()
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "ns", Time: time.Now()}, fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := msgpackDecode(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.([]any)[1].(map[string]any)
+	}
+
+	enc := log.NewForwardEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	enc.OpenNamespace("ns")
+	enc.AddString("a", "1")
+	child := enc.Clone()
+	child.AddString("c", "3")
+
+	got := decode(child, log.String("b", "2"))
+	if fmt.Sprint(got) != "map[msg:ns ns:map[a:1 b:2 c:3]]" {
+		t.Errorf("want the fields nested in the namespace, got: %v", got)
+	}
+	if got = decode(enc); fmt.Sprint(got) != "map[msg:ns ns:map[a:1]]" {
+		t.Errorf("want the parent not modified by the clone, got: %v", got)
+	}
+}
+
+func Test_Forward_PackedForward_Ack(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterForward),
+		log.WithForward(log.ForwardConfig{
+			Address:    ln.Addr().String(),
+			Tag:        "demo",
+			RequireAck: true,
+		}),
+	)
+	now := time.Now()
+	l.Named("svc").Infox("first", log.Int("n", 1), log.Any("tags", []string{"a", "b"}))
+	l.Named("svc").Warnx("second", log.Int("n", -300))
+
+	errc := make(chan error, 1)
+	go func() { errc <- l.Sync() }()
+
+	conn := acceptConn(t, ln)
+	r := bufio.NewReader(conn)
+	v, err := msgpackDecode(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := v.([]any)
+	if len(msg) != 3 || msg[0] != "demo.svc" {
+		t.Fatalf("want [tag, entries, option], got: %v", msg)
+	}
+	option := msg[2].(map[string]any)
+	if option["size"] != int64(2) {
+		t.Errorf("want size 2, got: %v", option)
+	}
+
+	entries := bufio.NewReader(bytes.NewReader(msg[1].([]byte)))
+	var records []map[string]any
+	for {
+		v, err := msgpackDecode(entries)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := v.([]any)
+		if ts := entry[0].(time.Time); ts.Before(now.Truncate(time.Second)) {
+			t.Errorf("want event time after %v, got: %v", now, ts)
+		}
+		records = append(records, entry[1].(map[string]any))
+	}
+	if len(records) != 2 {
+		t.Fatalf("want 2 records, got: %v", records)
+	}
+	if r := records[0]; r["msg"] != "first" || r["level"] != "info" || r["logger"] != "svc" || r["n"] != int64(1) || fmt.Sprint(r["tags"]) != "[a b]" {
+		t.Errorf("unexpected record: %v", r)
+	}
+	if r := records[1]; r["msg"] != "second" || r["level"] != "warn" || r["n"] != int64(-300) {
+		t.Errorf("unexpected record: %v", r)
+	}
+
+	// ack
+	chunk := option["chunk"].(string)
+	ack := append([]byte{0x81, 0xa3}, "ack"...)
+	ack = append(ack, 0xa0|byte(len(chunk)))
+	ack = append(ack, chunk...)
+	if _, err = conn.Write(ack); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sync not returned after ack")
+	}
+}
+
+func Test_Forward_Close(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterForward),
+		log.WithForward(log.ForwardConfig{
+			Address:       ln.Addr().String(),
+			FlushInterval: time.Hour,
+		}),
+	)
+	l.Warnx("closed")
+
+	errc := make(chan error, 1)
+	go func() { errc <- l.Close() }()
+
+	v, err := msgpackDecode(bufio.NewReader(acceptConn(t, ln)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := v.([]any); len(msg) != 3 || msg[0] != log.DefaultForwardTag {
+		t.Errorf("want the entry flushed on close, got: %v", msg)
+	}
+	select {
+	case err = <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close not returned")
+	}
+}
+
+func Test_Forward_Fallback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	fallback := filepath.Join(t.TempDir(), "fallback.log")
+	l := log.NewLogger(
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterForward),
+		log.WithForward(log.ForwardConfig{
+			Address:    addr,
+			MaxRetries: 1,
+			MinBackoff: time.Millisecond,
+		}),
+		log.WithFallback(fallback),
+	)
+	l.With(log.String("k", "v")).Warnx("unreachable")
+	_ = l.Close()
+
+	b, err := os.ReadFile(fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(b), "msg=unreachable k=v\n") {
+		t.Errorf("want logfmt entry in fallback, got: %q", b)
+	}
+}
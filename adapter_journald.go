@@ -0,0 +1,163 @@
+package log
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultJournaldSocket the default socket of systemd-journald native protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldConfig journald 适配器配置
+type JournaldConfig struct {
+	// SocketPath journald socket 路径, 默认 /run/systemd/journal/socket
+	SocketPath string `yaml:"socketPath" json:"socketPath"`
+	// Identifier SYSLOG_IDENTIFIER, 默认进程名
+	Identifier string `yaml:"identifier" json:"identifier"`
+}
+
+// journaldEncoder encode the entry as the journal native protocol, see
+// https://systemd.io/JOURNAL_NATIVE_PROTOCOL
+// level is mapped to PRIORITY, message to MESSAGE, caller to CODE_FILE/CODE_LINE/CODE_FUNC,
+// and fields to the uppercase journal fields, nested keys are joined with '_',
+// the field which collides with the journal fields, such as message, is prefixed with F_.
+type journaldEncoder struct {
+	*zapcore.MapObjectEncoder
+	ns         []string // the namespaces opened, the fields are added into the last one
+	cfg        *zapcore.EncoderConfig
+	identifier string
+}
+
+// NewJournaldEncoder creates a journald encoder.
+func NewJournaldEncoder(cfg zapcore.EncoderConfig, jc JournaldConfig) zapcore.Encoder {
+	identifier := jc.Identifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	return &journaldEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              &cfg,
+		identifier:       identifier,
+	}
+}
+
+func (enc *journaldEncoder) OpenNamespace(key string) {
+	enc.MapObjectEncoder.OpenNamespace(key)
+	enc.ns = append(enc.ns[:len(enc.ns):len(enc.ns)], key)
+}
+
+func (enc *journaldEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.MapObjectEncoder = cloneMapEncoder(enc.MapObjectEncoder, enc.ns)
+	return &clone
+}
+
+func (enc *journaldEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.Clone().(*journaldEncoder)
+	for i := range fields {
+		fields[i].AddTo(final.MapObjectEncoder)
+	}
+
+	buf := logfmtBufferPool.Get()
+	appendJournalField(buf, "PRIORITY", strconv.Itoa(SyslogSeverity(ent.Level)))
+	appendJournalField(buf, "MESSAGE", ent.Message)
+	appendJournalField(buf, "SYSLOG_IDENTIFIER", enc.identifier)
+	if ent.LoggerName != "" {
+		appendJournalField(buf, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		appendJournalField(buf, "CODE_FILE", ent.Caller.File)
+		appendJournalField(buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			appendJournalField(buf, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		appendJournalField(buf, "STACKTRACE", ent.Stack)
+	}
+	for _, f := range flattenFields(nil, "", final.Fields) {
+		appendJournalField(buf, journalFieldName(f.key), f.value)
+	}
+	return buf, nil
+}
+
+// journalFields the journal fields which are written by the encoder or have the special meaning,
+// see systemd.journal-fields(7).
+var journalFields = map[string]struct{}{
+	"MESSAGE":            {},
+	"MESSAGE_ID":         {},
+	"PRIORITY":           {},
+	"CODE_FILE":          {},
+	"CODE_LINE":          {},
+	"CODE_FUNC":          {},
+	"ERRNO":              {},
+	"INVOCATION_ID":      {},
+	"USER_INVOCATION_ID": {},
+	"SYSLOG_FACILITY":    {},
+	"SYSLOG_IDENTIFIER":  {},
+	"SYSLOG_PID":         {},
+	"SYSLOG_TIMESTAMP":   {},
+	"SYSLOG_RAW":         {},
+	"DOCUMENTATION":      {},
+	"TID":                {},
+	"UNIT":               {},
+	"USER_UNIT":          {},
+	"LOGGER":             {},
+	"STACKTRACE":         {},
+}
+
+// appendJournalField append the field with the journal native protocol,
+// the value contains newline is serialized in binary form:
+//
+//	KEY\n<little-endian uint64 size><value>\n
+func appendJournalField(buf *buffer.Buffer, key, value string) {
+	buf.AppendString(key)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf.AppendByte('=')
+		buf.AppendString(value)
+		buf.AppendByte('\n')
+		return
+	}
+	var size [8]byte
+
+	buf.AppendByte('\n')
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	_, _ = buf.Write(size[:])
+	buf.AppendString(value)
+	buf.AppendByte('\n')
+}
+
+// journalFieldName returns the valid journal field name, which only
+// contains uppercase letters, digits and underscores, starts with a letter,
+// and no longer than 64 characters.
+func journalFieldName(key string) string {
+	b := make([]byte, 0, len(key)+2)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			b = append(b, c-'a'+'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b = append(b, c)
+		default:
+			b = append(b, '_')
+		}
+	}
+	// fields starting with underscore are trusted fields, which are not allowed.
+	for len(b) > 0 && b[0] == '_' {
+		b = b[1:]
+	}
+	if _, reserved := journalFields[string(b)]; reserved || len(b) == 0 || (b[0] >= '0' && b[0] <= '9') {
+		b = append([]byte("F_"), b...)
+	}
+	if len(b) > 64 {
+		b = b[:64]
+	}
+	return string(b)
+}
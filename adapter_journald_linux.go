@@ -0,0 +1,99 @@
+//go:build linux
+
+package log
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// journaldWriter write the entry to the journald socket with datagram,
+// the large entry which exceeds the datagram size is sent by a sealed memfd.
+type journaldWriter struct {
+	mu   sync.Mutex
+	addr *net.UnixAddr
+	conn *net.UnixConn
+}
+
+func newJournaldWriter(jc JournaldConfig) *journaldWriter {
+	path := jc.SocketPath
+	if path == "" {
+		path = DefaultJournaldSocket
+	}
+	return &journaldWriter{
+		addr: &net.UnixAddr{Name: path, Net: "unixgram"},
+	}
+}
+
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	for i := 0; i < 2; i++ { // retry once with new socket.
+		if w.conn == nil {
+			// unbound socket, autobind by kernel.
+			if w.conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"}); err != nil {
+				continue
+			}
+		}
+		if _, _, err = w.conn.WriteMsgUnix(p, nil, w.addr); err == nil {
+			return len(p), nil
+		}
+		if isMsgTooLarge(err) {
+			// too large for datagram, send with memfd.
+			if err = w.writeMemfd(p); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *journaldWriter) writeMemfd(p []byte) error {
+	fd, err := unix.MemfdCreate("journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	for b := p; len(b) > 0; {
+		n, err := unix.Write(fd, b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	// journald requires the memfd is sealed.
+	_, err = unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL)
+	if err != nil {
+		return err
+	}
+	_, _, err = w.conn.WriteMsgUnix(nil, unix.UnixRights(fd), w.addr)
+	return err
+}
+
+func (w *journaldWriter) Sync() error { return nil }
+
+// Close closes the connection.
+func (w *journaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func isMsgTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
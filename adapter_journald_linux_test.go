@@ -0,0 +1,129 @@
+//go:build linux
+
+package log_test
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+func newJournaldListener(t *testing.T) (*net.UnixConn, string) {
+	addr := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram not supported: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, addr
+}
+
+// readJournal read a datagram, or the memfd passed with SCM_RIGHTS.
+func readJournal(t *testing.T, conn *net.UnixConn) []byte {
+	buf := make([]byte, 1<<20)
+	oob := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return buf[:n]
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "memfd")
+	defer f.Close()
+	// the offset is shared with the sender, which is at the end.
+	if _, err = f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err = b.ReadFrom(f); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func Test_Journald(t *testing.T) {
+	conn, addr := newJournaldListener(t)
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterJournald),
+		log.WithAddCaller(true),
+		log.WithJournald(log.JournaldConfig{SocketPath: addr, Identifier: "demo"}),
+	)
+	l.Named("svc").Warnx("hello", log.String("userId", "u1"), log.String("multi", "a\nb"))
+
+	got := string(readJournal(t, conn))
+	for _, want := range []string{
+		"PRIORITY=4\n",
+		"MESSAGE=hello\n",
+		"SYSLOG_IDENTIFIER=demo\n",
+		"LOGGER=svc\n",
+		"CODE_FILE=",
+		"CODE_LINE=",
+		"USERID=u1\n",
+		"MULTI\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want contains %q, got: %q", want, got)
+		}
+	}
+}
+
+func Test_Journald_ReservedField(t *testing.T) {
+	conn, addr := newJournaldListener(t)
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterJournald),
+		log.WithJournald(log.JournaldConfig{SocketPath: addr}),
+	)
+	l.With(log.String("message", "user"), log.Int("priority", 1), log.Namespace("req")).
+		Warnx("hello", log.String("id", "1"))
+
+	got := string(readJournal(t, conn))
+	for _, want := range []string{
+		"PRIORITY=4\n",
+		"MESSAGE=hello\n",
+		"F_MESSAGE=user\n",
+		"F_PRIORITY=1\n",
+		"REQ_ID=1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want contains %q, got: %q", want, got)
+		}
+	}
+	if strings.Count(got, "\nMESSAGE=") != 1 {
+		t.Errorf("want one MESSAGE, got: %q", got)
+	}
+}
+
+func Test_Journald_LargeEntry(t *testing.T) {
+	conn, addr := newJournaldListener(t)
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterJournald),
+		log.WithJournald(log.JournaldConfig{SocketPath: addr}),
+	)
+	large := strings.Repeat("x", 4<<20)
+	l.Infox("large", log.String("payload", large))
+
+	got := readJournal(t, conn)
+	want := "PAYLOAD=" + large + "\n"
+	if !bytes.Contains(got, []byte(want)) {
+		t.Errorf("want large payload sent with memfd, got size: %d", len(got))
+	}
+}
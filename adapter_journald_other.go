@@ -0,0 +1,21 @@
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+)
+
+// journaldWriter journald is only supported on linux.
+type journaldWriter struct{}
+
+func newJournaldWriter(JournaldConfig) *journaldWriter { return &journaldWriter{} }
+
+func (w *journaldWriter) Write([]byte) (int, error) {
+	return 0, errors.New("log: journald is only supported on linux")
+}
+
+func (w *journaldWriter) Sync() error { return nil }
+
+// Close closes the connection.
+func (w *journaldWriter) Close() error { return nil }
@@ -0,0 +1,421 @@
+package log
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// net framing defined
+const (
+	NetFramingNewline      = "newline"       // one entry per line
+	NetFramingLengthPrefix = "length-prefix" // 4-byte big-endian length followed by the entry without trailing newline
+)
+
+// ErrSpillFull the spill file exceeds the max size, the entry is dropped.
+var ErrSpillFull = errors.New("log: net spill file is full")
+
+// NetConfig net 适配器配置
+type NetConfig struct {
+	// Network 网络类型, tcp,udp,unix,unixgram 默认tcp
+	// udp,unixgram 每个日志一个数据报, 忽略 Framing
+	Network string `yaml:"network" json:"network"`
+	// Address 地址, 如 localhost:5170, /var/run/log.sock
+	Address string `yaml:"address" json:"address"`
+	// Framing 分帧方式, newline,length-prefix 默认newline
+	Framing string `yaml:"framing" json:"framing"`
+	// DialTimeout 连接超时, 默认5s
+	DialTimeout time.Duration `yaml:"dialTimeout" json:"dialTimeout"`
+	// WriteTimeout 写超时, 默认5s
+	WriteTimeout time.Duration `yaml:"writeTimeout" json:"writeTimeout"`
+	// MinBackoff 重连最小退避时间, 默认100ms, 每次失败加倍
+	MinBackoff time.Duration `yaml:"minBackoff" json:"minBackoff"`
+	// MaxBackoff 重连最大退避时间, 默认30s
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff"`
+	// SpillPath 远端不可用时的本地缓存文件, 默认空, 不缓存, 日志将丢弃,
+	// 重连退避期间丢弃的日志仅计数, 随下一次重连失败的错误报告
+	// 重连成功后, 缓存的日志由后台分块优先发送
+	SpillPath string `yaml:"spillPath" json:"spillPath"`
+	// SpillMaxSize 本地缓存文件最大尺寸(MB), 默认100MB
+	SpillMaxSize int `yaml:"spillMaxSize" json:"spillMaxSize"`
+	// TLS 如果配置该项, 则使用 tls 连接, 仅 tcp 有效
+	TLS *tls.Config `yaml:"-" json:"-"`
+}
+
+// backoff exponential backoff, the delay doubles on each failure until max.
+type backoff struct {
+	min, max time.Duration
+	cur      time.Duration
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+// Next returns the next delay.
+func (b *backoff) Next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.min
+	} else if b.cur *= 2; b.cur > b.max {
+		b.cur = b.max
+	}
+	return b.cur
+}
+
+// Reset resets the delay to min.
+func (b *backoff) Reset() { b.cur = 0 }
+
+// netReplayChunk the max bytes of the spilled entries replayed while holding the lock,
+// so the writers are not blocked by the replay of a large spill file.
+const netReplayChunk = 64 << 10
+
+// errNetBackoff the remote is not dialed until the backoff delay passed.
+var errNetBackoff = errors.New("log: net reconnect backoff")
+
+// netWriter write the entry to the remote with tcp, udp or unix socket,
+// it reconnects with exponential backoff when write failed, and the entries
+// are spilled to the local file while the remote is down, which are
+// replayed in order by the background goroutine after reconnected.
+type netWriter struct {
+	mu        sync.Mutex
+	nc        NetConfig
+	metrics   Metrics
+	datagram  bool
+	conn      net.Conn
+	backoff   *backoff
+	nextDial  time.Time
+	dropped   int // the entries dropped during the backoff without the spill file
+	spill     *os.File
+	spillHead int64 // the offset of the first entry not replayed
+	spillSize int64
+	frame     []byte
+	record    []byte
+	notify    chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func newNetWriter(nc NetConfig, m Metrics) *netWriter {
+	nc.Network = strings.ToLower(nc.Network)
+	if nc.Network == "" {
+		nc.Network = "tcp"
+	}
+	if nc.Framing == "" {
+		nc.Framing = NetFramingNewline
+	}
+	if nc.DialTimeout <= 0 {
+		nc.DialTimeout = 5 * time.Second
+	}
+	if nc.WriteTimeout <= 0 {
+		nc.WriteTimeout = 5 * time.Second
+	}
+	if nc.MinBackoff <= 0 {
+		nc.MinBackoff = 100 * time.Millisecond
+	}
+	if nc.MaxBackoff < nc.MinBackoff {
+		nc.MaxBackoff = 30 * time.Second
+	}
+	if nc.SpillMaxSize <= 0 {
+		nc.SpillMaxSize = 100
+	}
+	w := &netWriter{
+		nc:       nc,
+		metrics:  m,
+		datagram: strings.HasPrefix(nc.Network, "udp") || nc.Network == "unixgram",
+		backoff:  newBackoff(nc.MinBackoff, nc.MaxBackoff),
+	}
+	if nc.SpillPath != "" {
+		// open the spill file of the previous process before any write, so the live entries
+		// are spilled after the old ones instead of sent ahead of them. it is retried by Write if failed.
+		_ = w.openSpill()
+		w.notify = make(chan struct{}, 1)
+		w.done = make(chan struct{})
+		w.stopped = make(chan struct{})
+		// replay the entries spilled by the previous process.
+		w.notify <- struct{}{}
+		go w.run()
+	}
+	return w
+}
+
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// the entries are spilled until the replayer drained the spill file, keep the order.
+	if err := w.openSpill(); err != nil {
+		return 0, err
+	}
+	if w.spillHead == w.spillSize {
+		err := w.connect()
+		if err == nil {
+			if err = w.send(p); err == nil {
+				return len(p), nil
+			}
+			w.disconnect()
+		}
+		if w.nc.SpillPath == "" {
+			return w.drop(p, err)
+		}
+	}
+	if err := w.appendSpill(p); err != nil {
+		return 0, err
+	}
+	w.wakeup()
+	return len(p), nil
+}
+
+// drop drops the entry while the remote is down without the spill file, the entries
+// dropped during the backoff are only counted, and reported with the next dial error,
+// so the error is reported once per the backoff delay instead of each entry.
+func (w *netWriter) drop(p []byte, err error) (int, error) {
+	if errors.Is(err, errNetBackoff) {
+		w.dropped++
+		w.metrics.Dropped(DropSendFailed, 1)
+		return len(p), nil
+	}
+	if w.dropped > 0 {
+		err = fmt.Errorf("log: net dropped %d entries during reconnect backoff: %w", w.dropped, err)
+		w.dropped = 0
+	}
+	return 0, err
+}
+
+// Sync wakes up the replayer if any entries spilled, which are sent in the background.
+func (w *netWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.spillHead < w.spillSize {
+		w.wakeup()
+	}
+	return nil
+}
+
+// Close stops the replayer, closes the connection and the spill file, the spilled
+// entries not replayed are kept and replayed by the next writer with the same spill path.
+func (w *netWriter) Close() error {
+	w.closeOnce.Do(func() {
+		if w.done != nil {
+			close(w.done)
+			<-w.stopped
+		}
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	if w.spill != nil {
+		err = errors.Join(err, w.compactSpill(), w.spill.Close())
+		w.spill = nil
+	}
+	return err
+}
+
+// wakeup notifies the replayer without blocking.
+func (w *netWriter) wakeup() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run replays the spilled entries chunk by chunk when notified, until stopped.
+func (w *netWriter) run() {
+	defer close(w.stopped)
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.notify:
+		}
+		for {
+			delay, more := w.replay()
+			if !more {
+				break
+			}
+			if delay <= 0 {
+				continue
+			}
+			t := time.NewTimer(delay)
+			select {
+			case <-w.done:
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+	}
+}
+
+// connect dial the remote if not connected, it is not dialed until the backoff delay passed.
+func (w *netWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+	if now := time.Now(); now.Before(w.nextDial) {
+		return errNetBackoff
+	}
+	conn, err := w.dial()
+	if err != nil {
+		w.nextDial = time.Now().Add(w.backoff.Next())
+		return err
+	}
+	w.backoff.Reset()
+	w.conn = conn
+	return nil
+}
+
+func (w *netWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.nc.DialTimeout}
+	if w.nc.TLS != nil && strings.HasPrefix(w.nc.Network, "tcp") {
+		return tls.DialWithDialer(dialer, w.nc.Network, w.nc.Address, w.nc.TLS)
+	}
+	return dialer.Dial(w.nc.Network, w.nc.Address)
+}
+
+func (w *netWriter) disconnect() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	w.nextDial = time.Now().Add(w.backoff.Next())
+}
+
+// send write the entry with framing.
+func (w *netWriter) send(p []byte) error {
+	b := p
+	if w.datagram {
+		b = p[:len(p)-trailingNewline(p)]
+	} else if w.nc.Framing == NetFramingLengthPrefix {
+		b = p[:len(p)-trailingNewline(p)]
+		w.frame = binary.BigEndian.AppendUint32(w.frame[:0], uint32(len(b)))
+		w.frame = append(w.frame, b...)
+		b = w.frame
+	}
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.nc.WriteTimeout)); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(b)
+	return err
+}
+
+// openSpill open the spill file, which may contains the entries of the previous process.
+func (w *netWriter) openSpill() error {
+	if w.nc.SpillPath == "" || w.spill != nil {
+		return nil
+	}
+	f, err := os.OpenFile(w.nc.SpillPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.spill, w.spillHead, w.spillSize = f, 0, fi.Size()
+	return nil
+}
+
+// appendSpill append the entry to the spill file, each record is
+// 4-byte big-endian length followed by the entry.
+func (w *netWriter) appendSpill(p []byte) error {
+	if err := w.openSpill(); err != nil {
+		return err
+	}
+	maxSize := int64(w.nc.SpillMaxSize) << 20
+	if w.spillSize+int64(len(p))+4 > maxSize && w.spillHead > 0 {
+		// reclaim the replayed entries only when full, which copies the rest.
+		if err := w.compactSpill(); err != nil {
+			return err
+		}
+	}
+	if w.spillSize+int64(len(p))+4 > maxSize {
+		return ErrSpillFull
+	}
+	record := binary.BigEndian.AppendUint32(make([]byte, 0, len(p)+4), uint32(len(p)))
+	record = append(record, p...)
+	n, err := w.spill.Write(record)
+	w.spillSize += int64(n)
+	return err
+}
+
+// replay send a chunk of the spilled entries in order, it returns whether more
+// entries are pending, and the delay before the next chunk if the remote is down.
+// the spill file is truncated after all sent.
+func (w *netWriter) replay() (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.openSpill(); err != nil || w.spillHead == w.spillSize {
+		return 0, false
+	}
+	if err := w.connect(); err != nil {
+		return max(time.Until(w.nextDial), w.nc.MinBackoff), true
+	}
+	var header [4]byte
+	for sent := int64(0); sent < netReplayChunk && w.spillHead < w.spillSize; {
+		if _, err := w.spill.ReadAt(header[:], w.spillHead); err != nil {
+			// corrupted tail, such as the process crashed while writing, drop it.
+			w.spillHead = w.spillSize
+			break
+		}
+		size := int64(binary.BigEndian.Uint32(header[:]))
+		if w.spillHead+4+size > w.spillSize {
+			w.spillHead = w.spillSize
+			break
+		}
+		if int64(cap(w.record)) < size {
+			w.record = make([]byte, size)
+		}
+		p := w.record[:size]
+		if _, err := w.spill.ReadAt(p, w.spillHead+4); err != nil {
+			w.spillHead = w.spillSize
+			break
+		}
+		if err := w.send(p); err != nil {
+			w.disconnect()
+			return max(time.Until(w.nextDial), w.nc.MinBackoff), true
+		}
+		w.spillHead += 4 + size
+		sent += 4 + size
+	}
+	if w.spillHead < w.spillSize {
+		return 0, true
+	}
+	if err := w.spill.Truncate(0); err != nil {
+		return 0, false
+	}
+	w.spillHead, w.spillSize = 0, 0
+	return 0, false
+}
+
+// compactSpill remove the replayed entries from the head of the spill file.
+func (w *netWriter) compactSpill() error {
+	if w.spillHead == 0 {
+		return nil
+	}
+	var remain []byte
+	if w.spillHead < w.spillSize {
+		remain = make([]byte, w.spillSize-w.spillHead)
+		if _, err := w.spill.ReadAt(remain, w.spillHead); err != nil {
+			return err
+		}
+	}
+	if err := w.spill.Truncate(0); err != nil {
+		return err
+	}
+	n, err := w.spill.Write(remain)
+	w.spillHead, w.spillSize = 0, int64(n)
+	return err
+}
@@ -0,0 +1,234 @@
+package log_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+func acceptConn(t *testing.T, ln net.Listener) net.Conn {
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	return conn
+}
+
+func Test_Net_TCP_Newline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(log.NetConfig{Address: ln.Addr().String()}),
+	)
+	l.Infox("first", log.Int("n", 1))
+	l.Infox("second", log.Int("n", 2))
+
+	r := bufio.NewReader(acceptConn(t, ln))
+	for _, want := range []string{"msg=first n=1\n", "msg=second n=2\n"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(line, want) {
+			t.Errorf("want suffix %q, got: %q", want, line)
+		}
+	}
+}
+
+func Test_Net_TCP_LengthPrefix(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(log.NetConfig{
+			Address: ln.Addr().String(),
+			Framing: log.NetFramingLengthPrefix,
+		}),
+	)
+	l.Infox("hello")
+
+	conn := acceptConn(t, ln)
+	var header [4]byte
+	if _, err = io.ReadFull(conn, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	frame := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err = io.ReadFull(conn, frame); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(frame); !strings.HasSuffix(got, "msg=hello") {
+		t.Errorf("want frame without trailing newline, got: %q", got)
+	}
+}
+
+func Test_Net_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(log.NetConfig{Network: "udp", Address: conn.LocalAddr().String()}),
+	)
+	l.Infox("hello")
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); !strings.HasSuffix(got, "msg=hello") {
+		t.Errorf("want one datagram without trailing newline, got: %q", got)
+	}
+}
+
+func Test_Net_SpillAndReplay(t *testing.T) {
+	// reserve an address which is down.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(log.NetConfig{
+			Address:    addr,
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 10 * time.Millisecond,
+			SpillPath:  filepath.Join(t.TempDir(), "spill.log"),
+		}),
+	)
+	l.Infox("spilled", log.Int("n", 1))
+	l.Infox("spilled", log.Int("n", 2))
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("address reused failed: %v", err)
+	}
+	defer ln.Close()
+	time.Sleep(20 * time.Millisecond)
+	l.Infox("live", log.Int("n", 3))
+
+	r := bufio.NewReader(acceptConn(t, ln))
+	for _, want := range []string{"msg=spilled n=1\n", "msg=spilled n=2\n", "msg=live n=3\n"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(line, want) {
+			t.Errorf("want suffix %q, got: %q", want, line)
+		}
+	}
+}
+
+func Test_Net_DropDuringBackoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	var errs []error
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(log.NetConfig{
+			Address:    addr,
+			MinBackoff: time.Minute,
+		}),
+		log.WithErrorHandler(func(_ string, err error) { errs = append(errs, err) }),
+	)
+	defer l.Close()
+	for i := 0; i < 3; i++ {
+		l.Infox("dropped", log.Int("n", i))
+	}
+	if len(errs) != 1 {
+		t.Errorf("want the error reported once per backoff, got: %v", errs)
+	}
+}
+
+func Test_Net_CloseKeepSpill(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	nc := log.NetConfig{
+		Address:    addr,
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		SpillPath:  filepath.Join(t.TempDir(), "spill.log"),
+	}
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(nc),
+	)
+	l.Infox("spilled", log.Int("n", 1))
+	l.Infox("spilled", log.Int("n", 2))
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("address reused failed: %v", err)
+	}
+	defer ln.Close()
+	l = log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterNet),
+		log.WithNet(nc),
+	)
+	defer l.Close()
+	// the live entry is sent after the entries spilled by the previous writer.
+	l.Infox("live")
+
+	r := bufio.NewReader(acceptConn(t, ln))
+	for _, want := range []string{"msg=spilled n=1\n", "msg=spilled n=2\n", "msg=live\n"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(line, want) {
+			t.Errorf("want suffix %q, got: %q", want, line)
+		}
+	}
+}
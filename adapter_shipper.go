@@ -0,0 +1,454 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ShipperConfig http 批量推送适配器配置, adapter=loki,elasticsearch 使用
+type ShipperConfig struct {
+	// URL 推送地址
+	// loki: http://localhost:3100/loki/api/v1/push
+	// elasticsearch: http://localhost:9200/_bulk
+	URL string `yaml:"url" json:"url"`
+	// Headers 请求头, 如 Authorization, X-Scope-OrgID
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	// Labels loki 标签的字段key, 如 app,env, 默认只有 level 和 logger 标签
+	// 标签名中的非法字符替换为 _, 如 http.method 的标签名为 http_method
+	Labels []string `yaml:"labels" json:"labels"`
+	// StaticLabels loki 静态标签
+	StaticLabels map[string]string `yaml:"staticLabels" json:"staticLabels"`
+	// Index elasticsearch 索引, 支持 {} 包围的 go 时间格式, 如 logs-{2006.01.02}, 默认 logs-{2006.01.02}
+	Index string `yaml:"index" json:"index"`
+	// BatchSize 每批最大日志条数, 默认1000
+	BatchSize int `yaml:"batchSize" json:"batchSize"`
+	// BatchBytes 每批最大字节数, 默认1MB
+	BatchBytes int `yaml:"batchBytes" json:"batchBytes"`
+	// FlushInterval 最大推送间隔, 默认1s
+	FlushInterval time.Duration `yaml:"flushInterval" json:"flushInterval"`
+	// QueueSize 队列大小, 队列满时日志将丢弃, 默认10000
+	QueueSize int `yaml:"queueSize" json:"queueSize"`
+	// Timeout 请求超时, 默认10s
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// MaxRetries 5xx,429,网络错误时最大重试次数, 默认5
+	MaxRetries int `yaml:"maxRetries" json:"maxRetries"`
+	// MinBackoff 重试最小退避时间, 默认100ms, 每次失败加倍
+	MinBackoff time.Duration `yaml:"minBackoff" json:"minBackoff"`
+	// MaxBackoff 重试最大退避时间, 默认10s
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff"`
+	// Client http client, 默认 http.DefaultClient
+	Client *http.Client `yaml:"-" json:"-"`
+	// Metrics 如果配置该项, 则统计推送指标
+	Metrics *ShipperMetrics `yaml:"-" json:"-"`
+}
+
+// ShipperMetrics the metrics of the http shipper.
+type ShipperMetrics struct {
+	Entries atomic.Int64 // entries sent
+	Bytes   atomic.Int64 // request body bytes sent
+	Batches atomic.Int64 // batches sent
+	Retries atomic.Int64 // retried requests
+	Failed  atomic.Int64 // entries failed after retries or rejected by the remote
+	Dropped atomic.Int64 // entries dropped when the queue is full
+}
+
+// shipperEntry the encoded entry wait for sending.
+type shipperEntry struct {
+	time   time.Time
+	stream string // loki stream key, which is the sorted labels
+	labels map[string]string
+	line   []byte
+}
+
+// shipper batch the entries by size and time, and push them to loki or elasticsearch.
+type shipper struct {
+	kind    string
+	sc      ShipperConfig
+	metrics *ShipperMetrics
+	m       Metrics
+	labels  map[string]string // field key to loki label name
+	rescue  func(p []byte, err error) error
+	batcher *batcher[shipperEntry]
+}
+
+func newShipper(kind string, sc ShipperConfig, m Metrics, rescue func(p []byte, err error) error) *shipper {
+	if sc.Index == "" {
+		sc.Index = "logs-{2006.01.02}"
+	}
+	if sc.BatchSize <= 0 {
+		sc.BatchSize = 1000
+	}
+	if sc.BatchBytes <= 0 {
+		sc.BatchBytes = 1 << 20
+	}
+	if sc.FlushInterval <= 0 {
+		sc.FlushInterval = time.Second
+	}
+	if sc.QueueSize <= 0 {
+		sc.QueueSize = 10000
+	}
+	if sc.Timeout <= 0 {
+		sc.Timeout = 10 * time.Second
+	}
+	if sc.MaxRetries <= 0 {
+		sc.MaxRetries = 5
+	}
+	if sc.MinBackoff <= 0 {
+		sc.MinBackoff = 100 * time.Millisecond
+	}
+	if sc.MaxBackoff < sc.MinBackoff {
+		sc.MaxBackoff = 10 * time.Second
+	}
+	if sc.Client == nil {
+		sc.Client = http.DefaultClient
+	}
+	metrics := sc.Metrics
+	if metrics == nil {
+		metrics = &ShipperMetrics{}
+	}
+	labels := make(map[string]string, len(sc.Labels))
+	for _, k := range sc.Labels {
+		labels[k] = lokiLabelName(k)
+	}
+	s := &shipper{
+		kind:    kind,
+		sc:      sc,
+		metrics: metrics,
+		m:       m,
+		labels:  labels,
+		rescue:  rescue,
+	}
+	s.batcher = newBatcher(sc.QueueSize, sc.BatchSize, sc.BatchBytes, sc.FlushInterval,
+		func(e shipperEntry) int { return len(e.line) }, s.send)
+	return s
+}
+
+// push the entry into the queue, the entry is dropped when the queue is full.
+func (s *shipper) push(e shipperEntry) {
+	if !s.batcher.push(e) {
+		s.metrics.Dropped.Add(1)
+		s.m.Dropped(DropQueueFull, 1)
+	}
+}
+
+// Sync send all the entries in the queue, and wait for them sent.
+func (s *shipper) Sync() error { return s.batcher.Sync() }
+
+// Close send all the entries in the queue, and stop the shipper.
+func (s *shipper) Close() error { return s.batcher.Close() }
+
+// send the batch with retry, the entries are failed when retries exhausted or rejected.
+func (s *shipper) send(batch []shipperEntry) {
+	var (
+		body        []byte
+		contentType string
+	)
+	if s.kind == AdapterElasticsearch {
+		body, contentType = s.encodeBulk(batch), "application/x-ndjson"
+	} else {
+		body, contentType = s.encodeLoki(batch), "application/json"
+	}
+
+	start := time.Now()
+	bo := newBackoff(s.sc.MinBackoff, s.sc.MaxBackoff)
+	for i := 0; ; i++ {
+		retryAfter, err := s.post(body, contentType)
+		if err == nil {
+			s.metrics.Entries.Add(int64(len(batch)))
+			s.metrics.Bytes.Add(int64(len(body)))
+			s.metrics.Batches.Add(1)
+			s.m.Written(s.kind, len(body), time.Since(start))
+			return
+		}
+		s.m.SinkError(s.kind)
+		var re *retryableError
+		if !errors.As(err, &re) || i >= s.sc.MaxRetries {
+			s.fail(batch, err)
+			return
+		}
+		delay := bo.Next()
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		s.metrics.Retries.Add(1)
+		s.batcher.sleep(delay)
+	}
+}
+
+// fail the batch failed after retries or rejected, the entries are written to the Fallback if configured.
+func (s *shipper) fail(batch []shipperEntry, err error) {
+	s.metrics.Failed.Add(int64(len(batch)))
+	if s.rescue != nil {
+		var p []byte
+		for _, e := range batch {
+			p = append(p, e.line...)
+		}
+		if s.rescue(p, err) == nil {
+			return
+		}
+	}
+	s.m.Dropped(DropSendFailed, len(batch))
+}
+
+// retryableError the error can be retried, such as network error, 5xx and 429.
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func (s *shipper) post(body []byte, contentType string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.sc.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sc.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.sc.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.sc.Client.Do(req)
+	if err != nil {
+		return 0, &retryableError{err}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		var retryAfter time.Duration
+		if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(sec) * time.Second
+		}
+		return retryAfter, &retryableError{fmt.Errorf("log: shipper status %d: %s", resp.StatusCode, respBody)}
+	case resp.StatusCode >= 300:
+		return 0, fmt.Errorf("log: shipper status %d: %s", resp.StatusCode, respBody)
+	}
+	if s.kind == AdapterElasticsearch {
+		// elasticsearch bulk responds 200 even if some items failed.
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &result) == nil && result.Errors {
+			return 0, fmt.Errorf("log: shipper bulk errors: %s", respBody)
+		}
+	}
+	return 0, nil
+}
+
+// encodeLoki encode the batch as loki push request, entries are grouped by the labels.
+//
+//	{"streams":[{"stream":{"level":"info"},"values":[["<unix nano>","<line>"]]}]}
+func (s *shipper) encodeLoki(batch []shipperEntry) []byte {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	var streams []*stream
+	index := make(map[string]*stream)
+	for _, e := range batch {
+		st, ok := index[e.stream]
+		if !ok {
+			st = &stream{Stream: e.labels}
+			index[e.stream] = st
+			streams = append(streams, st)
+		}
+		st.Values = append(st.Values, [2]string{
+			strconv.FormatInt(e.time.UnixNano(), 10),
+			string(e.line[:len(e.line)-trailingNewline(e.line)]),
+		})
+	}
+	body, _ := json.Marshal(map[string][]*stream{"streams": streams})
+	return body
+}
+
+// encodeBulk encode the batch as elasticsearch bulk request, which is NDJSON.
+//
+//	{"create":{"_index":"logs-2006.01.02"}}
+//	<document>
+func (s *shipper) encodeBulk(batch []shipperEntry) []byte {
+	var buf bytes.Buffer
+	for _, e := range batch {
+		buf.WriteString(`{"create":{"_index":`)
+		index, _ := json.Marshal(shipperIndex(s.sc.Index, e.time))
+		buf.Write(index)
+		buf.WriteString("}}\n")
+		buf.Write(e.line[:len(e.line)-trailingNewline(e.line)])
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// shipperIndex returns the index name, the go time layout surrounded by {} is formatted with the time.
+func shipperIndex(tpl string, t time.Time) string {
+	start := strings.IndexByte(tpl, '{')
+	end := strings.IndexByte(tpl, '}')
+	if start < 0 || end < start {
+		return tpl
+	}
+	return tpl[:start] + t.UTC().Format(tpl[start+1:end]) + tpl[end+1:]
+}
+
+// shipperCore the core which encode the entry and push it to the shipper,
+// the loki labels are derived from the fields and the logger name.
+type shipperCore struct {
+	zapcore.LevelEnabler
+	enc     zapcore.Encoder
+	shipper *shipper
+	labels  map[string]string
+}
+
+func newShipperCore(enc zapcore.Encoder, enab zapcore.LevelEnabler, s *shipper) zapcore.Core {
+	labels := make(map[string]string, len(s.sc.StaticLabels))
+	for k, v := range s.sc.StaticLabels {
+		labels[lokiLabelName(k)] = v
+	}
+	return &shipperCore{
+		LevelEnabler: enab,
+		enc:          enc,
+		shipper:      s,
+		labels:       labels,
+	}
+}
+
+func (c *shipperCore) With(fields []Field) zapcore.Core {
+	clone := &shipperCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		shipper:      c.shipper,
+		labels:       c.labels,
+	}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	if c.hasLabel(fields) {
+		clone.labels = make(map[string]string, len(c.labels)+1)
+		for k, v := range c.labels {
+			clone.labels[k] = v
+		}
+		c.addLabels(clone.labels, fields)
+	}
+	return clone
+}
+
+func (c *shipperCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *shipperCore) Write(ent zapcore.Entry, fields []Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	e := shipperEntry{
+		time: ent.Time,
+		line: append([]byte(nil), buf.Bytes()...),
+	}
+	buf.Free()
+	if c.shipper.kind == AdapterLoki {
+		labels := make(map[string]string, len(c.labels)+2)
+		for k, v := range c.labels {
+			labels[k] = v
+		}
+		labels["level"] = ent.Level.String()
+		if ent.LoggerName != "" {
+			labels["logger"] = ent.LoggerName
+		}
+		if c.hasLabel(fields) {
+			c.addLabels(labels, fields)
+		}
+		e.labels, e.stream = labels, lokiStreamKey(labels)
+	}
+	c.shipper.push(e)
+	if ent.Level > zapcore.ErrorLevel {
+		// the process may exit, such as panic and fatal.
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *shipperCore) Sync() error { return c.shipper.Sync() }
+
+func (c *shipperCore) hasLabel(fields []Field) bool {
+	for _, f := range fields {
+		if _, ok := c.shipper.labels[f.Key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *shipperCore) addLabels(labels map[string]string, fields []Field) {
+	for _, f := range fields {
+		name, ok := c.shipper.labels[f.Key]
+		if !ok {
+			continue
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if v, ok := enc.Fields[f.Key]; ok {
+			labels[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+// lokiStreamKey returns the key of the labels, such as {app="demo",level="info"}.
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// lokiLabelName returns the valid loki label name, which matches [a-zA-Z_][a-zA-Z0-9_]*,
+// the invalid characters are replaced with '_', such as the dotted key http.method to http_method.
+func lokiLabelName(key string) string {
+	valid := func(i int, c byte) bool {
+		return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9'
+	}
+	for i := 0; i < len(key); i++ {
+		if valid(i, key[i]) {
+			continue
+		}
+		b := []byte(key)
+		for ; i < len(b); i++ {
+			if !valid(i, b[i]) {
+				b[i] = '_'
+			}
+		}
+		return string(b)
+	}
+	if key == "" {
+		return "_"
+	}
+	return key
+}
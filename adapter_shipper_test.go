@@ -0,0 +1,266 @@
+package log_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+func Test_Shipper_Loki(t *testing.T) {
+	type push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	var (
+		mu  sync.Mutex
+		got push
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("X-Scope-OrgID") != "tenant" {
+			t.Errorf("unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var metrics log.ShipperMetrics
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterLoki),
+		log.WithShipper(log.ShipperConfig{
+			URL:          srv.URL + "/loki/api/v1/push",
+			Headers:      map[string]string{"X-Scope-OrgID": "tenant"},
+			Labels:       []string{"env"},
+			StaticLabels: map[string]string{"app": "demo"},
+			Metrics:      &metrics,
+		}),
+	)
+	l = l.With(log.String("env", "prod"))
+	l.Infox("first")
+	l.Named("svc").Warnx("second")
+	l.Infox("third")
+	_ = l.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got.Streams) != 2 {
+		t.Fatalf("want 2 streams, got: %+v", got)
+	}
+	info, warn := got.Streams[0], got.Streams[1]
+	if info.Stream["app"] != "demo" || info.Stream["env"] != "prod" || info.Stream["level"] != "info" || len(info.Values) != 2 {
+		t.Errorf("unexpected info stream: %+v", info)
+	}
+	if warn.Stream["logger"] != "svc" || warn.Stream["level"] != "warn" || !strings.HasSuffix(warn.Values[0][1], "msg=second env=prod") {
+		t.Errorf("unexpected warn stream: %+v", warn)
+	}
+	if metrics.Entries.Load() != 3 || metrics.Batches.Load() != 1 {
+		t.Errorf("want 3 entries in 1 batch, got: %d, %d", metrics.Entries.Load(), metrics.Batches.Load())
+	}
+}
+
+func Test_Shipper_Elasticsearch_Retry(t *testing.T) {
+	var (
+		calls atomic.Int32
+		mu    sync.Mutex
+		lines []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("want ndjson, got: %s", ct)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		s := bufio.NewScanner(r.Body)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		_, _ = io.WriteString(w, `{"errors":false}`)
+	}))
+	defer srv.Close()
+
+	var metrics log.ShipperMetrics
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterElasticsearch),
+		log.WithShipper(log.ShipperConfig{
+			URL:        srv.URL + "/_bulk",
+			Index:      "app-{2006.01}",
+			MinBackoff: time.Millisecond,
+			Metrics:    &metrics,
+		}),
+	)
+	l.Infox("hello", log.Int("n", 1))
+	_ = l.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) != 2 {
+		t.Fatalf("want action and document, got: %q", lines)
+	}
+	wantAction := `{"create":{"_index":"app-` + time.Now().UTC().Format("2006.01") + `"}}`
+	if lines[0] != wantAction {
+		t.Errorf("want %s, got: %s", wantAction, lines[0])
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &doc); err != nil || doc["msg"] != "hello" {
+		t.Errorf("want json document, got: %s", lines[1])
+	}
+	if metrics.Retries.Load() != 1 || metrics.Entries.Load() != 1 {
+		t.Errorf("want 1 retry and 1 entry, got: %d, %d", metrics.Retries.Load(), metrics.Entries.Load())
+	}
+}
+
+func Test_Shipper_Rejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var metrics log.ShipperMetrics
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterLoki),
+		log.WithShipper(log.ShipperConfig{URL: srv.URL, Metrics: &metrics}),
+	)
+	l.Warnx("hello")
+	_ = l.Sync()
+	if metrics.Failed.Load() != 1 || metrics.Retries.Load() != 0 {
+		t.Errorf("want 1 failed without retry, got: %d, %d", metrics.Failed.Load(), metrics.Retries.Load())
+	}
+}
+
+func Test_Shipper_SyncWithoutBackoff(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var metrics log.ShipperMetrics
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterLoki),
+		log.WithShipper(log.ShipperConfig{
+			URL:        srv.URL,
+			MaxRetries: 2,
+			MinBackoff: time.Minute,
+			Metrics:    &metrics,
+		}),
+	)
+	defer l.Close()
+	l.Warnx("hello")
+
+	start := time.Now()
+	_ = l.Sync()
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("want Sync not blocked by the backoff, got: %s", d)
+	}
+	if calls.Load() != 3 || metrics.Failed.Load() != 1 {
+		t.Errorf("want 3 requests and 1 failed, got: %d, %d", calls.Load(), metrics.Failed.Load())
+	}
+}
+
+func Test_Shipper_Close(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		streams []map[string]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+			} `json:"streams"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, st := range got.Streams {
+			streams = append(streams, st.Stream)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterLoki),
+		log.WithShipper(log.ShipperConfig{
+			URL:           srv.URL,
+			Labels:        []string{"http.method"},
+			StaticLabels:  map[string]string{"k8s-app": "demo"},
+			FlushInterval: time.Hour,
+		}),
+	)
+	l.Warnx("hello", log.String("http.method", "GET"))
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(streams) != 1 || streams[0]["http_method"] != "GET" || streams[0]["k8s_app"] != "demo" {
+		t.Errorf("want the entry flushed on close with valid label names, got: %v", streams)
+	}
+}
+
+func Test_Shipper_Fallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var (
+		mu     sync.Mutex
+		failed []string
+	)
+	fallback := filepath.Join(t.TempDir(), "fallback.log")
+	l := log.NewLogger(
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterLoki),
+		log.WithShipper(log.ShipperConfig{URL: srv.URL}),
+		log.WithFallback(fallback),
+		log.WithErrorHandler(func(sink string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, sink)
+		}),
+	)
+	l.Warnx("rejected")
+	_ = l.Close()
+
+	b, err := os.ReadFile(fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "msg=rejected") {
+		t.Errorf("want entry in fallback, got: %s", b)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failed) != 1 || failed[0] != log.SinkLoki {
+		t.Errorf("want loki failed, got: %v", failed)
+	}
+}
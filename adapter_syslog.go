@@ -0,0 +1,434 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// syslog format defined
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+)
+
+// DefaultSyslogStructuredDataId the default SD-ID of the structured-data which hold fields.
+// 32473 is the private enterprise number reserved for documentation.
+const DefaultSyslogStructuredDataId = "fields@32473"
+
+// SyslogConfig syslog 适配器配置
+type SyslogConfig struct {
+	// Network 网络类型, unixgram,unix,udp,tcp, 默认空, 使用本地 /dev/log, tcp,unix 以换行分隔消息, 消息内换行转义为 #012
+	Network string `yaml:"network" json:"network"`
+	// Address 地址, 如 localhost:514, 默认空, 使用本地 /dev/log
+	Address string `yaml:"address" json:"address"`
+	// Format 格式, rfc5424,rfc3164, 默认 rfc5424
+	Format string `yaml:"format" json:"format"`
+	// Facility 设施, kern,user,mail,daemon,auth,syslog,lpr,news,uucp,cron,authpriv,ftp,local0~local7, 默认user
+	Facility string `yaml:"facility" json:"facility"`
+	// AppName 应用名, 默认进程名
+	AppName string `yaml:"appName" json:"appName"`
+	// StructuredDataId rfc5424 字段的 SD-ID, 默认 fields@32473
+	StructuredDataId string `yaml:"structuredDataId" json:"structuredDataId"`
+}
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// SyslogSeverity maps the level to syslog severity.
+func SyslogSeverity(l Level) int {
+	switch l {
+	case DebugLevel:
+		return 7 // debug
+	case InfoLevel:
+		return 6 // informational
+	case WarnLevel:
+		return 4 // warning
+	case ErrorLevel:
+		return 3 // error
+	case DPanicLevel:
+		return 2 // critical
+	case PanicLevel:
+		return 1 // alert
+	case FatalLevel:
+		return 0 // emergency
+	default:
+		return 5 // notice
+	}
+}
+
+// syslogEncoder encode the entry as a syslog message,
+// the fields are encoded as structured-data in rfc5424,
+// or appended to the message as key=value in rfc3164.
+type syslogEncoder struct {
+	*zapcore.MapObjectEncoder
+	ns       []string // the namespaces opened, the fields are added into the last one
+	cfg      *zapcore.EncoderConfig
+	format   string
+	facility int
+	hostname string
+	appName  string
+	pid      string
+	sdId     string
+}
+
+// NewSyslogEncoder creates a syslog encoder.
+func NewSyslogEncoder(cfg zapcore.EncoderConfig, sc SyslogConfig) zapcore.Encoder {
+	facility, ok := syslogFacilities[strings.ToLower(sc.Facility)]
+	if !ok {
+		facility = 1 // user
+	}
+	appName := sc.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	sdId := sc.StructuredDataId
+	if sdId == "" {
+		sdId = DefaultSyslogStructuredDataId
+	}
+	hostname, _ := os.Hostname()
+	format := strings.ToLower(sc.Format)
+	if format != SyslogRFC3164 {
+		format = SyslogRFC5424
+	}
+	return &syslogEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		cfg:              &cfg,
+		format:           format,
+		facility:         facility,
+		hostname:         hostname,
+		appName:          appName,
+		pid:              strconv.Itoa(os.Getpid()),
+		sdId:             sdId,
+	}
+}
+
+func (enc *syslogEncoder) OpenNamespace(key string) {
+	enc.MapObjectEncoder.OpenNamespace(key)
+	enc.ns = append(enc.ns[:len(enc.ns):len(enc.ns)], key)
+}
+
+func (enc *syslogEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.MapObjectEncoder = cloneMapEncoder(enc.MapObjectEncoder, enc.ns)
+	return &clone
+}
+
+func (enc *syslogEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.Clone().(*syslogEncoder)
+	for i := range fields {
+		fields[i].AddTo(final.MapObjectEncoder)
+	}
+	if ent.Caller.Defined && enc.cfg.CallerKey != "" {
+		final.Fields[enc.cfg.CallerKey] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" && enc.cfg.StacktraceKey != "" {
+		final.Fields[enc.cfg.StacktraceKey] = ent.Stack
+	}
+	params := flattenFields(nil, "", final.Fields)
+
+	buf := logfmtBufferPool.Get()
+	buf.AppendByte('<')
+	buf.AppendInt(int64(enc.facility*8 + SyslogSeverity(ent.Level)))
+	buf.AppendByte('>')
+	if enc.format == SyslogRFC3164 {
+		// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+		buf.AppendTime(ent.Time, time.Stamp)
+		buf.AppendByte(' ')
+		buf.AppendString(syslogHeader(enc.hostname, 255))
+		buf.AppendByte(' ')
+		buf.AppendString(syslogHeader(enc.appName, 32))
+		buf.AppendByte('[')
+		buf.AppendString(enc.pid)
+		buf.AppendString("]: ")
+		buf.AppendString(ent.Message)
+		for _, p := range params {
+			buf.AppendByte(' ')
+			appendLogfmtKey(buf, p.key)
+			buf.AppendByte('=')
+			appendLogfmtString(buf, p.value, false)
+		}
+	} else {
+		// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID PARAM="VALUE" ...] MSG
+		buf.AppendString("1 ")
+		buf.AppendTime(ent.Time, "2006-01-02T15:04:05.000000Z07:00")
+		buf.AppendByte(' ')
+		buf.AppendString(syslogHeader(enc.hostname, 255))
+		buf.AppendByte(' ')
+		buf.AppendString(syslogHeader(enc.appName, 48))
+		buf.AppendByte(' ')
+		buf.AppendString(enc.pid)
+		buf.AppendByte(' ')
+		buf.AppendString(syslogHeader(ent.LoggerName, 32))
+		buf.AppendByte(' ')
+		if len(params) == 0 {
+			buf.AppendByte('-')
+		} else {
+			buf.AppendByte('[')
+			buf.AppendString(enc.sdId)
+			for _, p := range params {
+				buf.AppendByte(' ')
+				buf.AppendString(syslogParamName(p.key))
+				buf.AppendString(`="`)
+				appendSyslogParamValue(buf, p.value)
+				buf.AppendByte('"')
+			}
+			buf.AppendByte(']')
+		}
+		if ent.Message != "" {
+			buf.AppendByte(' ')
+			buf.AppendString(ent.Message)
+		}
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// cloneMapEncoder copies the maps of the namespaces opened, which the fields are added into,
+// and reopens them, the other maps are never modified and shared.
+func cloneMapEncoder(enc *zapcore.MapObjectEncoder, ns []string) *zapcore.MapObjectEncoder {
+	clone := zapcore.NewMapObjectEncoder()
+	src, dst := enc.Fields, clone.Fields
+	for i := 0; ; i++ {
+		for k, v := range src {
+			if i < len(ns) && k == ns[i] {
+				continue
+			}
+			dst[k] = v
+		}
+		if i == len(ns) {
+			break
+		}
+		next, ok := src[ns[i]].(map[string]any)
+		if !ok {
+			break
+		}
+		clone.OpenNamespace(ns[i])
+		src, dst = next, dst[ns[i]].(map[string]any)
+	}
+	return clone
+}
+
+type syslogParam struct {
+	key   string
+	value string
+}
+
+// flattenFields flatten the fields into params with dotted keys, which are sorted.
+func flattenFields(params []syslogParam, prefix string, fields map[string]any) []syslogParam {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := fields[k].(type) {
+		case map[string]any:
+			params = flattenFields(params, key, v)
+		case string:
+			params = append(params, syslogParam{key, v})
+		case time.Time:
+			params = append(params, syslogParam{key, v.Format(time.RFC3339Nano)})
+		case time.Duration:
+			params = append(params, syslogParam{key, v.String()})
+		case []any, nil:
+			b, _ := json.Marshal(v)
+			params = append(params, syslogParam{key, string(b)})
+		default:
+			params = append(params, syslogParam{key, fmt.Sprint(v)})
+		}
+	}
+	return params
+}
+
+// syslogHeader returns the header field, which is printable ascii without space.
+func syslogHeader(s string, maxLen int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > maxLen {
+		b = b[:maxLen]
+	}
+	return string(b)
+}
+
+// syslogParamName returns the PARAM-NAME, which is printable ascii without '=', ' ', ']', '"'.
+func syslogParamName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	return string(b)
+}
+
+// appendSyslogParamValue append PARAM-VALUE, '"', '\' and ']' are escaped.
+func appendSyslogParamValue(buf *buffer.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			buf.AppendByte('\\')
+			buf.AppendByte(c)
+		default:
+			buf.AppendByte(c)
+		}
+	}
+}
+
+// syslogWriter write the syslog message to the syslog server,
+// it reconnects when write failed, and the dial is not retried until the backoff delay passed.
+type syslogWriter struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	conn     net.Conn
+	backoff  *backoff
+	nextDial time.Time
+}
+
+func newSyslogWriter(sc SyslogConfig) *syslogWriter {
+	return &syslogWriter{
+		network: strings.ToLower(sc.Network),
+		address: sc.Address,
+		backoff: newBackoff(100*time.Millisecond, 30*time.Second),
+	}
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	for i := 0; i < 2; i++ { // retry once with reconnect, such as the server restarted
+		// the network of the local syslog is resolved by dial, so the framing is chosen after connected.
+		if err = w.connect(); err != nil {
+			return 0, err
+		}
+		if _, err = w.conn.Write(w.frame(p)); err == nil {
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// frame returns the message framed for the network, the datagram carries one message
+// without the trailing newline. the stream is delimited by the newline (RFC 6587 non-transparent
+// framing), the embedded newlines are escaped as "#012", the same as rsyslog escapes the control characters.
+func (w *syslogWriter) frame(p []byte) []byte {
+	msg := p[:len(p)-trailingNewline(p)]
+	if !strings.HasPrefix(w.network, "tcp") && w.network != "unix" {
+		return msg
+	}
+	if bytes.IndexByte(msg, '\n') < 0 && len(msg) < len(p) {
+		return p
+	}
+	return append(bytes.ReplaceAll(msg, []byte{'\n'}, []byte("#012")), '\n')
+}
+
+// connect dial the server if not connected, it is not dialed until the backoff delay passed.
+func (w *syslogWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+	if time.Now().Before(w.nextDial) {
+		return errors.New("log: syslog reconnect backoff")
+	}
+	conn, err := w.dial()
+	if err != nil {
+		w.nextDial = time.Now().Add(w.backoff.Next())
+		return err
+	}
+	w.backoff.Reset()
+	w.conn = conn
+	return nil
+}
+
+func (w *syslogWriter) Sync() error { return nil }
+
+// Close closes the connection.
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *syslogWriter) dial() (net.Conn, error) {
+	if w.address != "" {
+		network := w.network
+		if network == "" {
+			network = "udp"
+		}
+		return net.DialTimeout(network, w.address, 5*time.Second)
+	}
+	// local syslog
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				w.network = network
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("log: unix syslog delivery error")
+}
+
+func trailingNewline(p []byte) int {
+	if n := len(p); n > 0 && p[n-1] == '\n' {
+		return 1
+	}
+	return 0
+}
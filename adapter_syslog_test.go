@@ -0,0 +1,140 @@
+package log_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+func Test_Syslog_RFC5424_Unixgram(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	if err != nil {
+		t.Skipf("unixgram not supported: %v", err)
+	}
+	defer conn.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterSyslog),
+		log.WithSyslog(log.SyslogConfig{
+			Network:  "unixgram",
+			Address:  addr,
+			Facility: "local0",
+			AppName:  "demo",
+		}),
+	)
+	l.Named("svc").Warnx("hello world", log.String("quote", `a"b]`), log.Int("n", 1))
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	// local0(16)*8 + warning(4)
+	if !strings.HasPrefix(got, "<132>1 ") {
+		t.Errorf("want PRI <132>1, got: %s", got)
+	}
+	want := " demo " + strconv.Itoa(os.Getpid()) + ` svc [fields@32473 n="1" quote="a\"b\]"] hello world`
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("want suffix %s, got: %s", want, got)
+	}
+}
+
+func Test_Syslog_RFC3164_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterSyslog),
+		log.WithSyslog(log.SyslogConfig{
+			Network: "tcp",
+			Address: ln.Addr().String(),
+			Format:  log.SyslogRFC3164,
+			AppName: "demo",
+		}),
+	)
+	go func() {
+		l.Errorx("hello", log.String("k", "v"))
+		l.Errorx("multi\nline")
+	}()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+	got, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	// user(1)*8 + error(3)
+	if !strings.HasPrefix(got, "<11>") || !strings.HasSuffix(got, " demo["+strconv.Itoa(os.Getpid())+"]: hello k=v\n") {
+		t.Errorf("unexpected rfc3164 message: %q", got)
+	}
+	// the embedded newline does not split the message.
+	if got, err = r.ReadString('\n'); err != nil || !strings.HasSuffix(got, ": multi#012line\n") {
+		t.Errorf("unexpected multi-line message: %q, %v", got, err)
+	}
+}
+
+func Test_Syslog_Namespace(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	if err != nil {
+		t.Skipf("unixgram not supported: %v", err)
+	}
+	defer conn.Close()
+
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterSyslog),
+		log.WithSyslog(log.SyslogConfig{Network: "unixgram", Address: addr}),
+	)
+	child := l.With(log.String("app", "demo"), log.Namespace("req"), log.String("id", "1"))
+	child.Warnx("first", log.String("k", "v"))
+	child.Warnx("second")
+
+	buf := make([]byte, 4096)
+	for _, want := range []string{
+		`[fields@32473 app="demo" req.id="1" req.k="v"] first`,
+		`[fields@32473 app="demo" req.id="1"] second`,
+	} {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); !strings.HasSuffix(got, want) {
+			t.Errorf("want suffix %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Syslog_ReconnectBackoff(t *testing.T) {
+	var errs []string
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterSyslog),
+		log.WithSyslog(log.SyslogConfig{Network: "unix", Address: filepath.Join(t.TempDir(), "missing.sock")}),
+		log.WithErrorHandler(func(_ string, err error) { errs = append(errs, err.Error()) }),
+	)
+	l.Warnx("first")
+	l.Warnx("second")
+	if len(errs) != 2 || strings.Contains(errs[0], "backoff") || !strings.Contains(errs[1], "backoff") {
+		t.Errorf("want dial error then backoff, got: %v", errs)
+	}
+}
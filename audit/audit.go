@@ -0,0 +1,318 @@
+// Package audit provides the append-only audit logger, which is separate from the application logs.
+// each record is fsynced before the call returns, and carries a monotonic sequence number and the
+// chain hash, which links the record to the previous one, so the truncated or altered audit file is
+// detected by Verify.
+//
+// the record is one json line, the hash is the last field:
+//
+//	{"ts":"...","level":"info","msg":"user.login","user":"alice","seq":1,"prev":"000...","hash":"4f2..."}
+//
+// hash = HMAC-SHA256(key, line before ,"hash") if the key is set, otherwise SHA-256,
+// the prev is the hash of the previous record, the prev of the first record is Genesis.
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+// Genesis the prev of the first record.
+var Genesis = strings.Repeat("0", sha256.Size*2)
+
+// audit field keys
+const (
+	SeqKey  = "seq"
+	PrevKey = "prev"
+	HashKey = "hash"
+)
+
+// ErrIncomplete the last record of the audit file is incomplete, such as the process crashed while writing.
+var ErrIncomplete = errors.New("audit: the last record is incomplete")
+
+// Config audit 配置
+type Config struct {
+	// Path 审计日志文件路径, 仅追加写入
+	Path string `yaml:"path" json:"path"`
+	// Key HMAC密钥, 为空时使用 SHA-256, 建议设置, 否则篡改者可重新计算整条哈希链
+	Key []byte `yaml:"-" json:"-"`
+	// Level 日志等级, 默认info
+	Level string `yaml:"level" json:"level"`
+}
+
+// Logger the audit logger, which never samples or drops the entries,
+// the entry is written synchronously and fsynced. If the write or fsync fails, the partial record
+// is truncated so the chain stays valid, and the first error is kept and returned by Sync and Close.
+type Logger struct {
+	*log.Log
+	core *core
+}
+
+// New opens the audit file and continues the chain of the last record.
+func New(c Config) (*Logger, error) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	if c.Level != "" {
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, err
+		}
+	}
+	seq, prev, err := lastRecord(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(c.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	ac := &core{
+		LevelEnabler: level,
+		enc:          zapcore.NewJSONEncoder(encoderConfig()),
+		shared: &chain{
+			file: f,
+			size: fi.Size(),
+			key:  c.Key,
+			seq:  seq,
+			prev: prev,
+		},
+	}
+	return &Logger{
+		Log:  log.NewLoggerWith(zap.New(ac, zap.ErrorOutput(zapcore.Lock(os.Stderr))), level),
+		core: ac,
+	}, nil
+}
+
+// Head returns the sequence number and the hash of the last record, which can be kept
+// outside of the audit file, such as the database, so the truncation of the tail is detected
+// by Verify with WithHead.
+func (l *Logger) Head() (seq uint64, hash string) {
+	l.core.shared.mu.Lock()
+	defer l.core.shared.mu.Unlock()
+	return l.core.shared.seq, l.core.shared.prev
+}
+
+// Close closes the audit file, the entries logged after Close fail.
+// it returns the first write error, if any record was lost.
+func (l *Logger) Close() error {
+	s := l.core.shared
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return errors.Join(s.err, s.file.Close())
+}
+
+func encoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		MessageKey:     "msg",
+		StacktraceKey:  zapcore.OmitKey,
+		CallerKey:      zapcore.OmitKey,
+		FunctionKey:    zapcore.OmitKey,
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+}
+
+// chain the state shared by the cores of the children loggers.
+type chain struct {
+	mu     sync.Mutex
+	file   *os.File
+	size   int64 // the offset after the last complete record
+	key    []byte
+	seq    uint64
+	prev   string
+	err    error // the first write error, which is sticky
+	broken bool  // the partial record can not be truncated, the later records are refused
+	closed bool
+}
+
+// core writes the entry with the sequence number and the chain hash.
+type core struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	shared *chain
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := &core{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		shared:       c.shared,
+	}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.shared
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	if s.broken {
+		return s.err
+	}
+
+	seq := s.seq + 1
+	fields = append(fields[:len(fields):len(fields)], zap.Uint64(SeqKey, seq), zap.String(PrevKey, s.prev))
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// the encoded line ends with "}\n", the hash is appended as the last field.
+	body := bytes.TrimSuffix(buf.Bytes(), []byte("}\n"))
+	sum := hex.EncodeToString(newHash(s.key, body))
+	line := make([]byte, 0, len(body)+len(sum)+16)
+	line = append(line, body...)
+	line = append(line, `,"`+HashKey+`":"`...)
+	line = append(line, sum...)
+	line = append(line, "\"}\n"...)
+
+	if _, err = s.file.Write(line); err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+	s.size += int64(len(line))
+	s.seq, s.prev = seq, sum
+	return nil
+}
+
+// fail keeps the error, and truncates the file back to the last complete record,
+// otherwise the later records are appended after the broken line and Verify fails.
+func (s *chain) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	if terr := s.file.Truncate(s.size); terr != nil {
+		s.err = errors.Join(s.err, terr)
+		s.broken = true
+	}
+}
+
+func (c *core) Sync() error {
+	s := c.shared
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	return errors.Join(s.err, s.file.Sync())
+}
+
+func newHash(key, body []byte) []byte {
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// record the chain fields of the record.
+type record struct {
+	Seq  uint64 `json:"seq"`
+	Prev string `json:"prev"`
+	Hash string `json:"hash"`
+}
+
+// splitRecord returns the body which is hashed and the chain fields of the line without line ending.
+func splitRecord(line []byte) ([]byte, record, error) {
+	var r record
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, r, err
+	}
+	suffix := `,"` + HashKey + `":"` + r.Hash + `"}`
+	if r.Hash == "" || !bytes.HasSuffix(line, []byte(suffix)) {
+		return nil, r, errors.New("audit: hash is not the last field")
+	}
+	return line[:len(line)-len(suffix)], r, nil
+}
+
+// lastRecord returns the sequence number and the hash of the last record of the file.
+func lastRecord(path string) (uint64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, Genesis, nil
+		}
+		return 0, "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	if fi.Size() == 0 {
+		return 0, Genesis, nil
+	}
+	// read backward until the start of the last line.
+	const chunk = 64 << 10
+	var tail []byte
+	for off := fi.Size(); off > 0; {
+		n := int64(chunk)
+		if off < n {
+			n = off
+		}
+		off -= n
+		b := make([]byte, n)
+		if _, err = f.ReadAt(b, off); err != nil && err != io.EOF {
+			return 0, "", err
+		}
+		tail = append(b, tail...)
+		if i := bytes.LastIndexByte(tail[:len(tail)-1], '\n'); i >= 0 {
+			tail = tail[i+1:]
+			break
+		}
+	}
+	if tail[len(tail)-1] != '\n' {
+		return 0, "", ErrIncomplete
+	}
+	_, r, err := splitRecord(bytes.TrimSuffix(tail, []byte("\n")))
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %v", ErrIncomplete, err)
+	}
+	return r.Seq, r.Hash, nil
+}
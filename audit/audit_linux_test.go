@@ -0,0 +1,66 @@
+//go:build linux
+
+package audit_test
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/audit"
+)
+
+// limitFileSize makes the writes beyond the size fail with EFBIG, the partial write is kept.
+func limitFileSize(t *testing.T, size uint64) (restore func()) {
+	t.Helper()
+	var old syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &old); err != nil {
+		t.Skip(err)
+	}
+	signal.Ignore(syscall.SIGXFSZ)
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &syscall.Rlimit{Cur: size, Max: old.Max}); err != nil {
+		t.Skip(err)
+	}
+	return func() {
+		_ = syscall.Setrlimit(syscall.RLIMIT_FSIZE, &old)
+		signal.Reset(syscall.SIGXFSZ)
+	}
+}
+
+func Test_Audit_WriteFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := writeRecords(t, path, 1)
+	defer l.Close()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restore := limitFileSize(t, uint64(fi.Size())+10)
+	l.Infox("user.logout", log.String("user", "alice"))
+	restore()
+	if fi2, _ := os.Stat(path); fi2.Size() != fi.Size() {
+		t.Errorf("want the partial record truncated to %d, got: %d", fi.Size(), fi2.Size())
+	}
+	if seq, _ := l.Head(); seq != 1 {
+		t.Errorf("want the lost record not chained, got seq: %d", seq)
+	}
+
+	l.Infox("user.login", log.String("user", "bob"))
+	if err = l.Sync(); err == nil {
+		t.Error("want the write error returned by Sync")
+	}
+	if err = l.Close(); err == nil {
+		t.Error("want the write error returned by Close")
+	}
+	res, err := audit.VerifyFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Records != 2 {
+		t.Errorf("want 2 records, got: %+v", res)
+	}
+}
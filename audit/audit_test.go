@@ -0,0 +1,154 @@
+package audit_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/audit"
+)
+
+var key = []byte("secret")
+
+func writeRecords(t *testing.T, path string, n int) *audit.Logger {
+	t.Helper()
+	l, err := audit.New(audit.Config{Path: path, Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		l.Infox("user.login", log.String("user", "alice"), log.Int("i", i))
+	}
+	return l
+}
+
+func readLines(t *testing.T, path string) [][]byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.SplitAfter(b, []byte("\n"))
+}
+
+func Test_Audit_Chain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := writeRecords(t, path, 3)
+	seq, hash := l.Head()
+	if seq != 3 {
+		t.Fatalf("want seq 3, got: %d", seq)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen continues the chain.
+	l = writeRecords(t, path, 2)
+	defer l.Close()
+	lines := readLines(t, path)
+	if !strings.Contains(string(lines[3]), `"seq":4,"prev":"`+hash+`"`) {
+		t.Errorf("want the chain continued, got: %s", lines[3])
+	}
+
+	res, err := audit.VerifyFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Records != 5 || res.Seq != 5 {
+		t.Errorf("want 5 records, got: %+v", res)
+	}
+	if _, err = audit.VerifyFile(path, []byte("wrong")); err == nil {
+		t.Error("want hash mismatch with the wrong key")
+	}
+}
+
+func Test_Audit_Tamper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := writeRecords(t, path, 4)
+	headSeq, headHash := l.Head()
+	_ = l.Close()
+	lines := readLines(t, path)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		opts  []audit.VerifyOption
+		check func(err error) bool
+	}{
+		{
+			name: "altered",
+			data: bytes.Join([][]byte{lines[0], bytes.Replace(lines[1], []byte("alice"), []byte("mallory"), 1), lines[2], lines[3]}, nil),
+			check: func(err error) bool {
+				var ve *audit.VerifyError
+				return errors.As(err, &ve) && ve.Line == 2 && strings.Contains(ve.Reason, "hash mismatch")
+			},
+		},
+		{
+			name: "removed",
+			data: bytes.Join([][]byte{lines[0], lines[2], lines[3]}, nil),
+			check: func(err error) bool {
+				var ve *audit.VerifyError
+				return errors.As(err, &ve) && ve.Line == 2 && strings.Contains(ve.Reason, "sequence gap")
+			},
+		},
+		{
+			name: "truncated tail",
+			data: bytes.Join([][]byte{lines[0], lines[1]}, nil),
+			opts: []audit.VerifyOption{audit.WithHead(headSeq, headHash)},
+			check: func(err error) bool {
+				var ve *audit.VerifyError
+				return errors.As(err, &ve) && strings.Contains(ve.Reason, "truncated")
+			},
+		},
+		{
+			name: "truncated head",
+			data: bytes.Join([][]byte{lines[2], lines[3]}, nil),
+			check: func(err error) bool {
+				var ve *audit.VerifyError
+				return errors.As(err, &ve) && ve.Line == 1
+			},
+		},
+		{
+			name:  "incomplete",
+			data:  bytes.Join([][]byte{lines[0], lines[1][:20]}, nil),
+			check: func(err error) bool { return errors.Is(err, audit.ErrIncomplete) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := audit.Verify(bytes.NewReader(tt.data), key, tt.opts...)
+			if !tt.check(err) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// the archived records are verified with the start.
+	res, err := audit.Verify(bytes.NewReader(lines[0]), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = audit.Verify(bytes.NewReader(bytes.Join(lines[1:], nil)), key, audit.WithStart(2, res.Hash)); err != nil {
+		t.Errorf("want verified from the start, got: %v", err)
+	}
+}
+
+func Test_Audit_IncompleteOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := writeRecords(t, path, 1)
+	_ = l.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"ts":"2024`)
+	_ = f.Close()
+
+	if _, err = audit.New(audit.Config{Path: path, Key: key}); !errors.Is(err, audit.ErrIncomplete) {
+		t.Errorf("want incomplete, got: %v", err)
+	}
+}
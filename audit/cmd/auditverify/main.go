@@ -0,0 +1,66 @@
+// Command auditverify verifies the chain of the audit files written by the audit package.
+//
+//	AUDIT_KEY=secret auditverify [-head seq:hash] audit.log
+//
+// the exit code is 0 if all the files are intact, 1 if any file is altered or truncated.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/things-go/log/audit"
+)
+
+func main() {
+	var (
+		keyFile string
+		head    string
+	)
+	flag.StringVar(&keyFile, "key-file", "", "the file of the HMAC key, default the environment AUDIT_KEY")
+	flag.StringVar(&head, "head", "", "the head recorded outside of the file, seq:hash, detects the truncation of the tail")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	key := []byte(os.Getenv("AUDIT_KEY"))
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		key = []byte(strings.TrimSpace(string(b)))
+	}
+	var opts []audit.VerifyOption
+	if head != "" {
+		seq, hash, ok := strings.Cut(head, ":")
+		n, err := strconv.ParseUint(seq, 10, 64)
+		if !ok || err != nil {
+			fmt.Fprintf(os.Stderr, "invalid head %q, want seq:hash\n", head)
+			os.Exit(2)
+		}
+		opts = append(opts, audit.WithHead(n, hash))
+	}
+
+	code := 0
+	for _, path := range flag.Args() {
+		res, err := audit.VerifyFile(path, key, opts...)
+		if err != nil {
+			fmt.Printf("%s: FAIL: %v\n", path, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("%s: OK, %d records, head %d:%s\n", path, res.Records, res.Seq, res.Hash)
+	}
+	os.Exit(code)
+}
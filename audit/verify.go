@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// VerifyError the record which breaks the chain.
+type VerifyError struct {
+	Line   int    // the line number, starts from 1
+	Seq    uint64 // the sequence number of the record, 0 if unknown
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("audit: line %d seq %d: %s", e.Line, e.Seq, e.Reason)
+}
+
+// Result the result of the verification.
+type Result struct {
+	Records int    // the number of the records verified
+	Seq     uint64 // the sequence number of the last record
+	Hash    string // the hash of the last record
+}
+
+// VerifyOption the option of Verify.
+type VerifyOption func(c *verifyConfig)
+
+type verifyConfig struct {
+	headSeq  uint64
+	headHash string
+	startSeq uint64
+	prev     string
+}
+
+// WithHead the head recorded by Logger.Head outside of the audit file,
+// the file which ends before the head, or has the other record at the head is reported.
+func WithHead(seq uint64, hash string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.headSeq = seq
+		c.headHash = hash
+	}
+}
+
+// WithStart the file starts from the record of the sequence number, whose prev is the hash,
+// such as the records before are archived.
+func WithStart(seq uint64, prev string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.startSeq = seq
+		c.prev = prev
+	}
+}
+
+// VerifyFile verifies the audit file, see Verify.
+func VerifyFile(path string, key []byte, opts ...VerifyOption) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+	return Verify(f, key, opts...)
+}
+
+// Verify verifies the chain of the records, it reports the first record which is altered,
+// inserted, removed or reordered as the *VerifyError, and ErrIncomplete if the last record is
+// incomplete. the truncation of the tail is reported only if WithHead is set.
+func Verify(r io.Reader, key []byte, opts ...VerifyOption) (Result, error) {
+	c := verifyConfig{startSeq: 1, prev: Genesis}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var (
+		res      Result
+		headSeen bool
+		br       = bufio.NewReaderSize(r, 64<<10)
+	)
+	res.Seq, res.Hash = c.startSeq-1, c.prev
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(b) > 0 {
+				return res, ErrIncomplete
+			}
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+
+		body, rec, err := splitRecord(bytes.TrimSuffix(b, []byte("\n")))
+		if err != nil {
+			return res, &VerifyError{Line: line, Seq: rec.Seq, Reason: "malformed record: " + err.Error()}
+		}
+		if rec.Seq != res.Seq+1 {
+			return res, &VerifyError{Line: line, Seq: rec.Seq, Reason: fmt.Sprintf("sequence gap, want %d", res.Seq+1)}
+		}
+		if rec.Prev != res.Hash {
+			return res, &VerifyError{Line: line, Seq: rec.Seq, Reason: "prev does not match the hash of the previous record"}
+		}
+		sum, err := hex.DecodeString(rec.Hash)
+		if err != nil || !hmac.Equal(sum, newHash(key, body)) {
+			return res, &VerifyError{Line: line, Seq: rec.Seq, Reason: "hash mismatch"}
+		}
+		if c.headSeq != 0 && rec.Seq == c.headSeq {
+			if rec.Hash != c.headHash {
+				return res, &VerifyError{Line: line, Seq: rec.Seq, Reason: "hash does not match the head"}
+			}
+			headSeen = true
+		}
+		res.Records++
+		res.Seq, res.Hash = rec.Seq, rec.Hash
+	}
+	if c.headSeq != 0 && !headSeen {
+		return res, &VerifyError{Line: res.Records + 1, Seq: c.headSeq, Reason: "truncated, the head is missing"}
+	}
+	return res, nil
+}
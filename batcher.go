@@ -0,0 +1,151 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batcher the queue of the async adapters, such as loki, elasticsearch and forward,
+// which batches the entries by count, bytes and time, and sends the batches in
+// the background goroutine until closed.
+type batcher[T any] struct {
+	queue      chan T
+	flush      chan chan struct{}
+	wake       chan struct{}
+	done       chan struct{}
+	stopped    chan struct{}
+	closeOnce  sync.Once
+	waiting    atomic.Int32 // the number of Sync and Close waiting
+	batchSize  int
+	batchBytes int
+	interval   time.Duration
+	size       func(T) int
+	send       func([]T)
+}
+
+func newBatcher[T any](queueSize, batchSize, batchBytes int, interval time.Duration, size func(T) int, send func([]T)) *batcher[T] {
+	b := &batcher[T]{
+		queue:      make(chan T, queueSize),
+		flush:      make(chan chan struct{}),
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+		batchSize:  batchSize,
+		batchBytes: batchBytes,
+		interval:   interval,
+		size:       size,
+		send:       send,
+	}
+	go b.run()
+	return b
+}
+
+// push the entry into the queue, it returns false if the queue is full, the entry is dropped.
+func (b *batcher[T]) push(e T) bool {
+	select {
+	case b.queue <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sync send all the entries in the queue, and wait for them sent,
+// the batches failed are retried without the backoff delay while Sync waiting.
+func (b *batcher[T]) Sync() error {
+	b.waiting.Add(1)
+	defer b.waiting.Add(-1)
+	b.wakeup()
+
+	done := make(chan struct{})
+	select {
+	case b.flush <- done:
+	case <-b.stopped:
+		return nil
+	}
+	<-done
+	return nil
+}
+
+// Close send all the entries in the queue, and stop the goroutine.
+func (b *batcher[T]) Close() error {
+	b.waiting.Add(1)
+	defer b.waiting.Add(-1)
+	b.closeOnce.Do(func() { close(b.done) })
+	<-b.stopped
+	return nil
+}
+
+// sleep waits for the retry delay, it returns immediately if Sync or Close is waiting,
+// so they are not blocked behind the retry delay, the batch is retried without delay.
+func (b *batcher[T]) sleep(d time.Duration) {
+	if b.waiting.Load() > 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			return
+		case <-b.done:
+			return
+		case <-b.wake:
+			if b.waiting.Load() > 0 {
+				return
+			}
+		}
+	}
+}
+
+func (b *batcher[T]) wakeup() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	var (
+		batch []T
+		size  int
+	)
+	sendAll := func() {
+		if len(batch) > 0 {
+			b.send(batch)
+			batch, size = batch[:0], 0
+		}
+	}
+	add := func(e T) {
+		batch = append(batch, e)
+		size += b.size(e)
+		if len(batch) >= b.batchSize || size >= b.batchBytes {
+			sendAll()
+		}
+	}
+	drain := func() {
+		for n := len(b.queue); n > 0; n-- {
+			add(<-b.queue)
+		}
+		sendAll()
+	}
+	for {
+		select {
+		case e := <-b.queue:
+			add(e)
+		case <-ticker.C:
+			sendAll()
+		case done := <-b.flush:
+			drain()
+			close(done)
+		case <-b.done:
+			drain()
+			return
+		}
+	}
+}
@@ -250,3 +250,18 @@ func Benchmark_Json_Format_Use_Hook(b *testing.B) {
 		).InfofContext(ctx, "success")
 	}
 }
+
+func Benchmark_Json_Logger_Use_GenericValuer(b *testing.B) {
+	b.ReportAllocs()
+	b.StopTimer()
+	logger := newDiscardLogger(log.FormatJson)
+	logger.SetDefaultValuer(
+		log.From("name", func(context.Context) string { return "jack" }),
+		log.From("age", func(context.Context) int { return 18 }),
+	)
+	ctx := context.Background()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoxContext(ctx, "success")
+	}
+}
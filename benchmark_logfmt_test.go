@@ -0,0 +1,42 @@
+package log_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/things-go/log"
+)
+
+func Benchmark_Logfmt_Logger(b *testing.B) {
+	b.ReportAllocs()
+	b.StopTimer()
+	logger := newDiscardLogger(log.FormatLogfmt)
+	ctx := context.Background()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoxContext(
+			ctx,
+			"success",
+			log.String("name", "jack"),
+			log.Int("age", 18),
+			dfltCtx(ctx),
+		)
+	}
+}
+
+func Benchmark_Logfmt_Logger_Use_Hook(b *testing.B) {
+	b.ReportAllocs()
+	b.StopTimer()
+	logger := newDiscardLogger(log.FormatLogfmt)
+	logger.SetDefaultValuer(dfltCtx)
+	ctx := context.Background()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoxContext(
+			ctx,
+			"success",
+			log.String("name", "jack"),
+			log.Int("age", 18),
+		)
+	}
+}
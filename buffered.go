@@ -0,0 +1,148 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type bufferedKey struct{}
+
+// BufferedOption the option of BeginBuffered.
+type BufferedOption func(rb *requestBuffer)
+
+// WithBufferedLatency the buffered entries are emitted if the request
+// takes longer than the threshold, default 0, only emitted on error.
+func WithBufferedLatency(threshold time.Duration) BufferedOption {
+	return func(rb *requestBuffer) { rb.latency = threshold }
+}
+
+// WithBufferedMaxEntries the max entries buffered, the oldest is dropped when exceeded, default 1000.
+func WithBufferedMaxEntries(n int) BufferedOption {
+	return func(rb *requestBuffer) {
+		if n > 0 {
+			rb.maxEntries = n
+		}
+	}
+}
+
+// requestBuffer buffer the entries below the level of a request.
+type requestBuffer struct {
+	mu         sync.Mutex
+	start      time.Time
+	latency    time.Duration
+	maxEntries int
+	finished   bool
+	items      recorderRing                // the oldest is overwritten when full
+	loggers    map[*zap.Logger]*zap.Logger // the logger which core buffer the entries
+}
+
+// BeginBuffered returns a context which buffers the entries below the level
+// logged with the *Context methods, such as DebugxContext, the buffered entries
+// are emitted by Finish only if the request ends with an error or exceeds the
+// latency threshold, otherwise discarded.
+//
+//	ctx = log.BeginBuffered(ctx, log.WithBufferedLatency(time.Second))
+//	defer func() { log.Finish(ctx, err) }()
+func BeginBuffered(ctx context.Context, opts ...BufferedOption) context.Context {
+	rb := &requestBuffer{
+		start:      time.Now(),
+		maxEntries: 1000,
+		loggers:    make(map[*zap.Logger]*zap.Logger),
+	}
+	for _, opt := range opts {
+		opt(rb)
+	}
+	return context.WithValue(ctx, bufferedKey{}, rb)
+}
+
+// Finish emits the buffered entries if err is not nil or the latency exceeds the threshold,
+// otherwise discards them, it reports whether the entries are emitted.
+// the entries logged after Finish are not buffered.
+func Finish(ctx context.Context, err error) bool {
+	rb := bufferFromContext(ctx)
+	if rb == nil {
+		return false
+	}
+	rb.mu.Lock()
+	if rb.finished {
+		rb.mu.Unlock()
+		return false
+	}
+	rb.finished = true
+	items := rb.items.appendTo(nil)
+	rb.items, rb.loggers = recorderRing{}, nil
+	rb.mu.Unlock()
+
+	if err == nil && (rb.latency <= 0 || time.Since(rb.start) < rb.latency) {
+		return false
+	}
+	for _, item := range items {
+		_ = item.core.Write(item.ent, item.fields)
+	}
+	return true
+}
+
+func bufferFromContext(ctx context.Context) *requestBuffer {
+	if ctx == nil {
+		return nil
+	}
+	rb, _ := ctx.Value(bufferedKey{}).(*requestBuffer)
+	return rb
+}
+
+// logger returns the logger which buffers the entries of all levels, nil if finished.
+func (rb *requestBuffer) logger(l *zap.Logger) *zap.Logger {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.finished {
+		return nil
+	}
+	bl, ok := rb.loggers[l]
+	if !ok {
+		bl = l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &bufferedCore{core: core, buffer: rb}
+		}))
+		rb.loggers[l] = bl
+	}
+	return bl
+}
+
+func (rb *requestBuffer) add(item recorderItem) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.finished {
+		return
+	}
+	rb.items.push(item, rb.maxEntries)
+}
+
+// bufferedCore enables all the levels, and buffers the entries instead of writing.
+type bufferedCore struct {
+	core   zapcore.Core
+	buffer *requestBuffer
+}
+
+func (c *bufferedCore) Enabled(zapcore.Level) bool { return true }
+
+func (c *bufferedCore) With(fields []Field) zapcore.Core {
+	return &bufferedCore{core: c.core.With(fields), buffer: c.buffer}
+}
+
+func (c *bufferedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *bufferedCore) Write(ent zapcore.Entry, fields []Field) error {
+	c.buffer.add(recorderItem{
+		core:   c.core,
+		ent:    ent,
+		fields: append([]Field(nil), fields...),
+	})
+	return nil
+}
+
+func (c *bufferedCore) Sync() error { return c.core.Sync() }
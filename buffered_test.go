@@ -0,0 +1,87 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+func Test_Buffered_FlushOnError(t *testing.T) {
+	l, buf := newBufferLogger(
+		log.WithLevel("warn"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAddCaller(true),
+		log.WithCallerSkip(2), // xxxContext -> Logx
+	)
+	valuerCalled := 0
+	l.SetDefaultValuer(func(ctx context.Context) log.Field {
+		valuerCalled++
+		return log.String("requestId", "r1")
+	})
+
+	ctx := log.BeginBuffered(context.Background())
+	l.DebugxContext(ctx, "step 1")
+	l.InfowContext(ctx, "step 2", "k", "v")
+	l.Debugx("not buffered without context")
+	l.WarnxContext(ctx, "warn")
+	if got := lines(buf.String()); len(got) != 1 || !strings.Contains(got[0], "msg=warn") {
+		t.Fatalf("want only warn written before finished, got: %q", got)
+	}
+
+	if !log.Finish(ctx, errors.New("failed")) {
+		t.Fatal("want emitted on error")
+	}
+	got := lines(buf.String())
+	if len(got) != 3 {
+		t.Fatalf("want 3 lines, got: %q", got)
+	}
+	if !strings.Contains(got[1], `msg="step 1" requestId=r1`) || !strings.Contains(got[2], `msg="step 2" requestId=r1 k=v`) {
+		t.Errorf("unexpected buffered entries: %q", got[1:])
+	}
+	if !strings.Contains(got[1], "buffered_test.go:") {
+		t.Errorf("want caller of the call-site, got: %s", got[1])
+	}
+	if valuerCalled != 3 {
+		t.Errorf("want Valuer evaluated once per entry, got: %d", valuerCalled)
+	}
+
+	// not buffered after finished.
+	l.DebugxContext(ctx, "after finished")
+	if log.Finish(ctx, errors.New("failed")) || strings.Contains(buf.String(), "after finished") {
+		t.Errorf("want not buffered after finished, got: %s", buf.String())
+	}
+}
+
+func Test_Buffered_Discard(t *testing.T) {
+	l, buf := newBufferLogger(log.WithLevel("warn"))
+
+	ctx := log.BeginBuffered(context.Background())
+	l.DebugxContext(ctx, "step")
+	if log.Finish(ctx, nil) || buf.Len() != 0 {
+		t.Errorf("want discarded without error, got: %s", buf.String())
+	}
+
+	ctx = log.BeginBuffered(context.Background(), log.WithBufferedLatency(time.Millisecond))
+	l.DebugxContext(ctx, "slow")
+	time.Sleep(2 * time.Millisecond)
+	if !log.Finish(ctx, nil) || !strings.Contains(buf.String(), "slow") {
+		t.Errorf("want emitted when exceeds the latency, got: %s", buf.String())
+	}
+}
+
+func Test_Buffered_MaxEntries(t *testing.T) {
+	l, buf := newBufferLogger(log.WithLevel("warn"), log.WithFormat(log.FormatLogfmt))
+	ctx := log.BeginBuffered(context.Background(), log.WithBufferedMaxEntries(2))
+	for _, msg := range []string{"step1", "step2", "step3", "step4"} {
+		l.DebugxContext(ctx, msg)
+	}
+	log.Finish(ctx, errors.New("failed"))
+	got := lines(buf.String())
+	if len(got) != 2 || !strings.Contains(got[0], "msg=step3") || !strings.Contains(got[1], "msg=step4") {
+		t.Errorf("want the last 2 entries in order, got: %q", got)
+	}
+}
@@ -9,7 +9,14 @@ import (
 	"go.uber.org/zap"
 )
 
-const loggerPackage = "mod.miligc.com/edge-common/logger"
+// loggerPackage the source directory of this package, the frames in it, but not in
+// the sub packages, are skipped when find the caller. Valuers are evaluated lazily inside
+// Logx/Logw through callValuer, so the depth from the Valuer to the call site differs
+// between the logging methods, a fixed depth can not reach the call site.
+var loggerPackage = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file[:strings.LastIndexByte(file, '/')+1]
+}()
 
 type CallerCore struct {
 	level        AtomicLevel
@@ -129,7 +136,7 @@ func skipPackage(file string, skipPackages ...string) bool {
 	if strings.HasSuffix(file, "_test.go") {
 		return false
 	}
-	if strings.Contains(file, loggerPackage) {
+	if strings.HasPrefix(file, loggerPackage) && strings.IndexByte(file[len(loggerPackage):], '/') < 0 {
 		return true
 	}
 	for _, p := range skipPackages {
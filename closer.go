@@ -0,0 +1,65 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// closers the background goroutines and the connections of the adapters,
+// such as the spill replayer of the net adapter, which are closed once in reverse order.
+type closers struct {
+	mu     sync.Mutex
+	list   []io.Closer
+	closed bool
+	err    error
+}
+
+// add adds the closer, it is safe to call on nil.
+func (cs *closers) add(c io.Closer) {
+	if cs == nil {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.list = append(cs.list, c)
+}
+
+func (cs *closers) Close() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.closed {
+		return cs.err
+	}
+	cs.closed = true
+	for i := len(cs.list) - 1; i >= 0; i-- {
+		cs.err = errors.Join(cs.err, cs.list[i].Close())
+	}
+	return cs.err
+}
+
+// closeCore the root core which closes the closers, see Log.Close.
+type closeCore struct {
+	zapcore.Core
+	closers *closers
+}
+
+func newCloseCore(core zapcore.Core, cs *closers) zapcore.Core {
+	if len(cs.list) == 0 {
+		return core
+	}
+	return &closeCore{Core: core, closers: cs}
+}
+
+func (c *closeCore) With(fields []Field) zapcore.Core {
+	return &closeCore{Core: c.Core.With(fields), closers: c.closers}
+}
+
+func (c *closeCore) recording() bool { return isRecording(c.Core) }
+
+// Close flushes the buffered entries, then closes the closers.
+func (c *closeCore) Close() error {
+	return errors.Join(c.Core.Sync(), c.closers.Close())
+}
@@ -0,0 +1,303 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxOverrideCores the maximum cores cached for the scoped keys overridden by keep-last.
+const maxOverrideCores = 16
+
+// dedupCore is a zapcore.Core that removes the duplicate keys across
+// With fields, Valuer fields and call-site fields before encoding.
+// the duplicates of With fields are resolved once at With time, and the fields
+// are encoded by the underlying core as usual. the fields of the entry are only compared
+// with the keys of the With fields in the innermost namespace, the entry which overrides
+// them with keep-last is written by the core built without the overridden fields, which is cached.
+type dedupCore struct {
+	core       zapcore.Core // the core with the With fields
+	base       zapcore.Core // the core without the With fields
+	policy     string
+	fields     []Field             // the resolved With fields
+	scope      int                 // start index of the innermost namespace in fields
+	keys       map[string]struct{} // the keys of the innermost namespace in fields
+	duplicates []string            // the duplicate keys of With fields
+	overrides  *overrideCores      // the cores without the overridden keys, only for keep-last
+}
+
+// overrideCores the cores built without the scoped keys overridden by keep-last, by the keys.
+type overrideCores struct {
+	mu    sync.Mutex
+	cores map[string]zapcore.Core
+}
+
+func newDedupCore(core zapcore.Core, policy string) zapcore.Core {
+	switch policy {
+	case DuplicateKeyKeepLast, DuplicateKeyKeepFirst, DuplicateKeyRename, DuplicateKeyError:
+		return &dedupCore{core: core, base: core, policy: policy}
+	default:
+		return core
+	}
+}
+
+func (c *dedupCore) Enabled(lvl zapcore.Level) bool { return c.core.Enabled(lvl) }
+
+func (c *dedupCore) With(fields []Field) zapcore.Core {
+	fs := make([]Field, 0, len(c.fields)+len(fields))
+	fs = append(fs, c.fields...)
+	fs = append(fs, fields...)
+	overridden := overridesPrefix(c.policy, fs, len(c.fields))
+	fs, duplicates := dedupFields(c.policy, fs)
+
+	clone := &dedupCore{
+		base:       c.base,
+		policy:     c.policy,
+		fields:     fs,
+		duplicates: append(c.duplicates[:len(c.duplicates):len(c.duplicates)], duplicates...),
+	}
+	if overridden {
+		// keep-last dropped some encoded fields.
+		clone.core = c.base.With(fs)
+	} else {
+		// only the new fields are added or renamed, the encoded fields are kept.
+		clone.core = c.core.With(fs[len(c.fields):])
+	}
+	for i, f := range fs {
+		if f.Type == zapcore.NamespaceType {
+			clone.scope = i + 1
+		}
+	}
+	if scoped := fs[clone.scope:]; len(scoped) > 0 {
+		clone.keys = make(map[string]struct{}, len(scoped))
+		for _, f := range scoped {
+			if f.Key != "" {
+				clone.keys[f.Key] = struct{}{}
+			}
+		}
+	}
+	if c.policy == DuplicateKeyKeepLast {
+		clone.overrides = &overrideCores{}
+	}
+	return clone
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []Field) error {
+	fc := poolGet()
+	defer poolPut(fc)
+	fc.Fields = append(fc.Fields, fields...)
+	fs, overridden, duplicates := c.dedupEntry(fc.Fields)
+
+	core := c.core
+	if len(overridden) > 0 {
+		core = c.overrideCore(overridden)
+	}
+	err := checkWrite(core, ent, fs)
+	if c.policy == DuplicateKeyError && len(c.duplicates)+len(duplicates) > 0 {
+		duplicates = append(c.duplicates[:len(c.duplicates):len(c.duplicates)], duplicates...)
+		err = multierr.Append(err, fmt.Errorf("log: duplicate keys %q in entry %q", duplicates, ent.Message))
+	}
+	return err
+}
+
+// dedupEntry removes the duplicate keys of the entry fields in place with the policy, the keys
+// before the first namespace of the entry are compared with the scoped keys of With as well.
+// it returns the scoped keys overridden by keep-last and the duplicate keys.
+func (c *dedupCore) dedupEntry(fields []Field) ([]Field, []string, []string) {
+	var overridden, duplicates []string
+
+	out := fields[:0]
+	scope := 0 // start index of current namespace in out
+	taken := func(key string) bool {
+		if containsKey(out[scope:], key) {
+			return true
+		}
+		_, ok := c.keys[key]
+		return ok && scope == 0
+	}
+	for i, f := range fields {
+		if f.Type == zapcore.SkipType {
+			continue
+		}
+		if f.Key != "" {
+			if c.policy == DuplicateKeyKeepLast {
+				if overriddenLater(f.Key, fields[i+1:]) {
+					duplicates = append(duplicates, f.Key)
+					continue
+				}
+				if _, ok := c.keys[f.Key]; ok && scope == 0 {
+					overridden = append(overridden, f.Key)
+				}
+			} else if taken(f.Key) {
+				duplicates = append(duplicates, f.Key)
+				if c.policy != DuplicateKeyRename {
+					continue
+				}
+				f.Key = renameKey(f.Key, taken)
+			}
+		}
+		out = append(out, f)
+		if f.Type == zapcore.NamespaceType {
+			scope = len(out)
+		}
+	}
+	return out, overridden, duplicates
+}
+
+// overrideCore returns the core with the With fields except the overridden scoped keys,
+// the With fields are encoded once for the same keys.
+func (c *dedupCore) overrideCore(keys []string) zapcore.Core {
+	sort.Strings(keys)
+	id := strings.Join(keys, "\x00")
+	c.overrides.mu.Lock()
+	core, ok := c.overrides.cores[id]
+	c.overrides.mu.Unlock()
+	if ok {
+		return core
+	}
+
+	fs := make([]Field, 0, len(c.fields))
+	fs = append(fs, c.fields[:c.scope]...)
+	for _, f := range c.fields[c.scope:] {
+		if i := sort.SearchStrings(keys, f.Key); i == len(keys) || keys[i] != f.Key {
+			fs = append(fs, f)
+		}
+	}
+	core = c.base.With(fs)
+
+	c.overrides.mu.Lock()
+	if c.overrides.cores == nil {
+		c.overrides.cores = make(map[string]zapcore.Core)
+	}
+	if len(c.overrides.cores) < maxOverrideCores {
+		c.overrides.cores[id] = core
+	}
+	c.overrides.mu.Unlock()
+	return core
+}
+
+// checkWrite writes the entry through the Check of the core, so the filters of the core,
+// such as the level and the sampler, still apply, the write error is returned.
+func checkWrite(core zapcore.Core, ent zapcore.Entry, fields []Field) error {
+	ce := core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	out := &errorOutput{}
+	ce.ErrorOutput = out
+	ce.Write(fields...)
+	return out.err
+}
+
+// errorOutput collects the write error reported by CheckedEntry.Write.
+type errorOutput struct{ err error }
+
+func (o *errorOutput) Write(p []byte) (int, error) {
+	msg := p
+	if i := bytes.Index(msg, []byte(" write error: ")); i >= 0 {
+		msg = msg[i+len(" write error: "):]
+	}
+	o.err = multierr.Append(o.err, errors.New(string(bytes.TrimSuffix(msg, []byte("\n")))))
+	return len(p), nil
+}
+
+func (*errorOutput) Sync() error { return nil }
+
+func (c *dedupCore) Sync() error     { return c.core.Sync() }
+func (c *dedupCore) recording() bool { return isRecording(c.core) }
+
+// dedupFields removes the duplicate keys in place with the policy,
+// and returns the duplicate keys.
+// keys are only compared in the same namespace, the field without key
+// (such as Inline) is never treated as duplicate.
+func dedupFields(policy string, fields []Field) ([]Field, []string) {
+	var duplicates []string
+
+	out := fields[:0]
+	scope := 0 // start index of current namespace in out
+	for i, f := range fields {
+		if f.Type == zapcore.SkipType {
+			continue
+		}
+		if f.Key != "" {
+			if policy == DuplicateKeyKeepLast {
+				if overriddenLater(f.Key, fields[i+1:]) {
+					duplicates = append(duplicates, f.Key)
+					continue
+				}
+			} else if containsKey(out[scope:], f.Key) {
+				duplicates = append(duplicates, f.Key)
+				if policy != DuplicateKeyRename {
+					continue
+				}
+				f.Key = renameKey(f.Key, func(k string) bool { return containsKey(out[scope:], k) })
+			}
+		}
+		out = append(out, f)
+		if f.Type == zapcore.NamespaceType {
+			scope = len(out)
+		}
+	}
+	return out, duplicates
+}
+
+// overriddenLater reports whether the key appears again in the
+// same namespace of the rest fields.
+func overriddenLater(key string, rest []Field) bool {
+	for _, f := range rest {
+		if f.Key == key && f.Type != zapcore.SkipType {
+			return true
+		}
+		if f.Type == zapcore.NamespaceType {
+			return false
+		}
+	}
+	return false
+}
+
+// overridesPrefix reports whether keep-last drops any of the first n fields,
+// the other policies always keep the former fields.
+func overridesPrefix(policy string, fields []Field, n int) bool {
+	if policy != DuplicateKeyKeepLast {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if fields[i].Key != "" && overriddenLater(fields[i].Key, fields[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(fields []Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// renameKey returns a new key with the suffix "_<n>", which is not taken.
+func renameKey(key string, taken func(string) bool) string {
+	for n := 1; ; n++ {
+		k := key + "_" + strconv.Itoa(n)
+		if !taken(k) {
+			return k
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package log_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+func Test_DuplicateKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"none", "", `"traceId":"with","traceId":"valuer","traceId":"call"`},
+		{"keep-last", log.DuplicateKeyKeepLast, `"msg":"dup","traceId":"call"}`},
+		{"keep-first", log.DuplicateKeyKeepFirst, `"msg":"dup","traceId":"with"}`},
+		{"rename", log.DuplicateKeyRename, `"traceId":"with","traceId_1":"valuer","traceId_2":"call"`},
+		{"error", log.DuplicateKeyError, `"msg":"dup","traceId":"with"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newBufferLogger(log.WithDuplicateKey(tt.policy))
+			l.WithValuer(log.ImmutString("traceId", "valuer")).
+				With(log.String("traceId", "with")).
+				Infox("dup", log.String("traceId", "call"))
+			if got := buf.String(); !strings.Contains(got, tt.want) {
+				t.Errorf("want contains %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_DuplicateKey_Namespace(t *testing.T) {
+	l, buf := newBufferLogger(log.WithDuplicateKey(log.DuplicateKeyKeepFirst))
+	l.Infox("dup",
+		log.String("k", "v1"),
+		log.Namespace("ns"),
+		log.String("k", "v2"),
+		log.String("k", "v3"),
+	)
+	want := `"k":"v1","ns":{"k":"v2"}}`
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("want contains %s, got: %s", want, got)
+	}
+}
+
+type countMarshaler struct{ n *int }
+
+func (m countMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	*m.n++
+	enc.AddString("k", "v")
+	return nil
+}
+
+func Test_DuplicateKey_With(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"keep-last", log.DuplicateKeyKeepLast, `"obj":{"k":"v"},"ns":{"a":"2","b":"call"}}`},
+		{"keep-first", log.DuplicateKeyKeepFirst, `"obj":{"k":"v"},"ns":{"a":"1","b":"with"}}`},
+		{"rename", log.DuplicateKeyRename, `"obj":{"k":"v"},"ns":{"a":"1","b":"with","a_1":"2","b_1":"call"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var encoded int
+			l, buf := newBufferLogger(log.WithDuplicateKey(tt.policy))
+			l = l.With(log.Object("obj", countMarshaler{&encoded}), log.Namespace("ns"), log.String("a", "1")).
+				With(log.String("b", "with"), log.String("a", "2"))
+			for i := 0; i < 3; i++ {
+				buf.Reset()
+				l.Infox("dup", log.String("b", "call"))
+				if got := buf.String(); !strings.Contains(got, tt.want) {
+					t.Errorf("want contains %s, got: %s", tt.want, got)
+				}
+			}
+			// keep-last rebuilds the core once for the overridden "a" of With,
+			// and once for the "b" overridden by the entries.
+			want := 1
+			if tt.policy == log.DuplicateKeyKeepLast {
+				want = 3
+			}
+			if encoded != want {
+				t.Errorf("want With fields encoded %d times, got: %d", want, encoded)
+			}
+		})
+	}
+}
@@ -95,12 +95,21 @@ func Logger() *zap.Logger { return defaultLogger.Logger() }
 // panics in development and errors in production.
 func With(fields ...Field) *Log { return defaultLogger.With(fields...) }
 
+// Withw creates a child logger with loosely-typed key-value pairs. See Log.Withw for details.
+func Withw(keysAndValues ...any) *Log { return defaultLogger.Withw(keysAndValues...) }
+
+// WithHooks creates a child logger with the hooks. See Log.WithHooks for details.
+func WithHooks(hooks ...Hook) *Log { return defaultLogger.WithHooks(hooks...) }
+
 // Named adds a sub-scope to the logger's name. See Log.Named for details.
 func Named(name string) *Log { return defaultLogger.Named(name) }
 
 // Sync flushes any buffered log entries.
 func Sync() error { return defaultLogger.Sync() }
 
+// Close flushes any buffered log entries, and closes the adapters.
+func Close() error { return defaultLogger.Close() }
+
 // ****** named after the log level or ending in "Context" for log.Print-style logging
 
 func Debug(args ...any) {
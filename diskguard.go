@@ -0,0 +1,272 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// diskGuard guard the disk usage of the file adapter, the janitor periodically
+// removes the oldest backups until the total size is within MaxTotalSize, and
+// checks the free space of the disk, the debug and info entries are dropped
+// while the free space is below MinFreeSpace.
+type diskGuard struct {
+	filename     string
+	maxTotalSize int64
+	minFreeSpace uint64
+	interval     time.Duration
+	low          atomic.Bool
+	metrics      Metrics
+	core         zapcore.Core // the file core to emit the warning
+	done         chan struct{}
+	stopped      chan struct{}
+	closeOnce    sync.Once
+}
+
+// newDiskGuard returns nil if neither MaxTotalSize nor MinFreeSpace configured.
+func newDiskGuard(c *Config) *diskGuard {
+	if c.MaxTotalSize <= 0 && c.MinFreeSpace <= 0 {
+		return nil
+	}
+	filename := filepath.Join(c.Path, c.Filename)
+	if filename == "" {
+		// same as lumberjack
+		filename = filepath.Join(os.TempDir(), filepath.Base(os.Args[0])+"-lumberjack.log")
+	}
+	interval := c.JanitorInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &diskGuard{
+		filename:     filename,
+		maxTotalSize: int64(c.MaxTotalSize) << 20,
+		minFreeSpace: uint64(c.MinFreeSpace) << 20,
+		interval:     interval,
+		metrics:      toMetrics(c),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+// newCore creates the core with the file sink guarded, the other sinks are not affected.
+func (g *diskGuard) newCore(c *Config, enc zapcore.Encoder, enab zapcore.LevelEnabler) zapcore.Core {
+	g.core = zapcore.NewCore(enc, toFileWriter(c), enab)
+	core := zapcore.Core(&diskGuardCore{Core: g.core, guard: g})
+
+	other := *c
+	switch strings.ToLower(c.Adapter) {
+	case AdapterMulti:
+		other.Adapter = AdapterConsole
+	case AdapterFileCustom:
+		other.Adapter = AdapterCustom
+	case AdapterMultiCustom:
+		other.Adapter = AdapterConsoleCustom
+	default:
+		other.Adapter = ""
+	}
+	if other.Adapter != "" && (other.Adapter != AdapterCustom || len(c.Writer) > 0) {
+		core = newTeeCore(zapcore.NewCore(enc.Clone(), toWriter(&other), enab), core)
+	}
+
+	g.check()
+	go g.run()
+	c.closers.add(g)
+	return core
+}
+
+func (g *diskGuard) run() {
+	defer close(g.stopped)
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-ticker.C:
+			g.check()
+		}
+	}
+}
+
+// Close stops the janitor.
+func (g *diskGuard) Close() error {
+	g.closeOnce.Do(func() { close(g.done) })
+	<-g.stopped
+	return nil
+}
+
+// check enforce the total size and the free space.
+func (g *diskGuard) check() {
+	if g.maxTotalSize > 0 {
+		g.enforceTotalSize()
+	}
+	if g.minFreeSpace == 0 {
+		return
+	}
+	free, err := diskFree(filepath.Dir(g.filename))
+	if err != nil {
+		return
+	}
+	low := free < g.minFreeSpace
+	if g.low.Swap(low) == low {
+		return
+	}
+	msg := "log: disk free space recovered, debug and info entries are written"
+	if low {
+		msg = "log: disk free space is low, debug and info entries are dropped"
+	}
+	_ = g.core.Write(zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Now(),
+		Message: msg,
+	}, []Field{zap.Uint64("freeSpace", free), zap.Uint64("minFreeSpace", g.minFreeSpace)})
+}
+
+// enforceTotalSize remove the oldest backups until the total size of
+// the current file and the backups is within the max total size.
+func (g *diskGuard) enforceTotalSize() {
+	backups, total := g.backups()
+	if fi, err := os.Stat(g.filename); err == nil {
+		total += fi.Size()
+	}
+	for i := 0; i < len(backups) && total > g.maxTotalSize; i++ {
+		if err := os.Remove(backups[i].path); err == nil {
+			total -= backups[i].size
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	size int64
+	time time.Time
+}
+
+// backupTimeFormat the timestamp of the backup name of lumberjack.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// parseBackupTime returns the timestamp of the backup name <prefix><timestamp><ext>,
+// which may be compressed with the suffix .gz or .zst, false if not a backup.
+func parseBackupTime(name, prefix, ext string) (time.Time, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+	ts := name[len(prefix):]
+	for _, suffix := range []string{".gz", ".zst"} {
+		if strings.HasSuffix(ts, ext+suffix) {
+			ts = strings.TrimSuffix(ts, suffix)
+			break
+		}
+	}
+	if !strings.HasSuffix(ts, ext) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeFormat, ts[:len(ts)-len(ext)])
+	return t, err == nil
+}
+
+// backups returns the backups of the file sorted by the oldest first, and the total size of them.
+func (g *diskGuard) backups() ([]backupFile, int64) { return listBackups(g.filename) }
+
+// listBackups returns the backups of the file sorted by the oldest first, and the total size of them.
+// the backup name of lumberjack is <name>-<timestamp><ext>, which may be compressed,
+// the other files with the same prefix are not backups, such as app-worker.log of app.log.
+func listBackups(filename string) ([]backupFile, int64) {
+	dir := filepath.Dir(filename)
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0
+	}
+	var (
+		backups []backupFile
+		total   int64
+	)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		t, ok := parseBackupTime(e.Name(), prefix, ext)
+		if !ok {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{filepath.Join(dir, e.Name()), fi.Size(), t})
+		total += fi.Size()
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.Before(backups[j].time) })
+	return backups, total
+}
+
+// diskGuardCore drops the debug and info entries while the free space is low.
+type diskGuardCore struct {
+	zapcore.Core
+	guard *diskGuard
+}
+
+func (c *diskGuardCore) With(fields []Field) zapcore.Core {
+	return &diskGuardCore{Core: c.Core.With(fields), guard: c.guard}
+}
+
+// drops reports whether the entry is dropped while the free space is low.
+func (c *diskGuardCore) drops(ent zapcore.Entry) bool {
+	return ent.Level < zapcore.WarnLevel && c.guard.low.Load()
+}
+
+func (c *diskGuardCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.drops(ent) {
+		c.guard.metrics.Dropped(DropDiskLow, 1)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Write drops the entries written directly, such as dumped by the flight recorder.
+func (c *diskGuardCore) Write(ent zapcore.Entry, fields []Field) error {
+	if c.drops(ent) {
+		c.guard.metrics.Dropped(DropDiskLow, 1)
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// teeCore the tee of the guarded file sink and the other sink, the entry is dropped
+// only if all the cores drop it, see entryDropper.
+type teeCore struct {
+	zapcore.Core
+	cores []zapcore.Core
+}
+
+func newTeeCore(cores ...zapcore.Core) zapcore.Core {
+	return &teeCore{Core: zapcore.NewTee(cores...), cores: cores}
+}
+
+func (c *teeCore) With(fields []Field) zapcore.Core {
+	cores := make([]zapcore.Core, len(c.cores))
+	for i, core := range c.cores {
+		cores[i] = core.With(fields)
+	}
+	return newTeeCore(cores...)
+}
+
+func (c *teeCore) drops(ent zapcore.Entry) bool {
+	for _, core := range c.cores {
+		if d, ok := core.(entryDropper); !ok || !d.drops(ent) {
+			return false
+		}
+	}
+	return true
+}
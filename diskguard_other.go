@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package log
+
+import (
+	"errors"
+)
+
+// diskFree the free space is not supported, the MinFreeSpace is ignored.
+func diskFree(string) (uint64, error) {
+	return 0, errors.New("log: disk free space is not supported")
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package log
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// diskFree returns the free space available to unprivileged users of the disk which holds the path.
+func diskFree(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,84 @@
+package log_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+func Test_DiskGuard_MaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	backups := []string{
+		"app-2024-01-01T00-00-00.000.log.gz",
+		"app-2024-01-02T00-00-00.000.log",
+		"app-2024-01-03T00-00-00.000.log",
+	}
+	for i, name := range backups {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, 1<<20), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i-len(backups)) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	others := []string{"other.log", "app-worker.log", "app-worker-2024-01-01T00-00-00.000.log"}
+	for _, name := range others {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 1<<20), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterFile),
+		log.WithPath(dir),
+		log.WithFilename("app.log"),
+		log.WithMaxTotalSize(2),
+	)
+	defer l.Close()
+	for i, name := range backups {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if removed := os.IsNotExist(err); removed != (i == 0) {
+			t.Errorf("%s: want only the oldest removed, got removed: %v", name, removed)
+		}
+	}
+	for _, name := range others {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: want other files kept, got: %v", name, err)
+		}
+	}
+}
+
+func Test_DiskGuard_MinFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+	l := log.NewLogger(
+		log.WithLevel("debug"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterFile),
+		log.WithPath(dir),
+		log.WithFilename("app.log"),
+		log.WithMinFreeSpace(1<<40), // always low
+	)
+	l.Debugx("dropped debug")
+	l.Infox("dropped info")
+	l.Warnx("kept warn")
+	_ = l.Sync()
+
+	b, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	if strings.Contains(got, "dropped debug") || strings.Contains(got, "dropped info") {
+		t.Errorf("want debug and info dropped, got: %s", got)
+	}
+	if !strings.Contains(got, "disk free space is low") || !strings.Contains(got, "kept warn") {
+		t.Errorf("want warning and warn entry, got: %s", got)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package log
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// diskFree returns the free space available to the caller of the disk which holds the path.
+func diskFree(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err = windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
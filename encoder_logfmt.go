@@ -0,0 +1,598 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const logfmtHex = "0123456789abcdef"
+
+var (
+	logfmtBufferPool = buffer.NewPool()
+	logfmtPool       = sync.Pool{
+		New: func() any { return &logfmtEncoder{} },
+	}
+)
+
+// logfmtEncoder encode the entry as logfmt, such as:
+//
+//	ts=2006-01-02T15:04:05Z level=info msg="hello world" key=val
+//
+// nested objects are flattened with dotted keys, such as obj.key=val,
+// arrays are rendered as [v1,v2,...].
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf        *buffer.Buffer
+	namespaces []string
+	// pretty is set by the pretty encoder, which colors the keys
+	// and collects the multi-line values into the trailer.
+	pretty  *prettyConfig
+	trailer *buffer.Buffer
+}
+
+// NewLogfmtEncoder creates a logfmt encoder.
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{
+		EncoderConfig: &cfg,
+		buf:           logfmtBufferPool.Get(),
+	}
+}
+
+func getLogfmtEncoder(cfg *zapcore.EncoderConfig) *logfmtEncoder {
+	enc := logfmtPool.Get().(*logfmtEncoder)
+	enc.EncoderConfig = cfg
+	enc.buf = logfmtBufferPool.Get()
+	enc.namespaces = enc.namespaces[:0]
+	enc.pretty = nil
+	enc.trailer = nil
+	return enc
+}
+
+func putLogfmtEncoder(enc *logfmtEncoder) {
+	enc.EncoderConfig = nil
+	enc.buf = nil
+	enc.namespaces = enc.namespaces[:0]
+	enc.pretty = nil
+	enc.trailer = nil
+	logfmtPool.Put(enc)
+}
+
+func (enc *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	buf := logfmtBufferPool.Get()
+	defer buf.Free()
+	err := enc.encodeArray(buf, arr)
+	enc.addKey(key)
+	appendLogfmtString(enc.buf, buf.String(), false)
+	return err
+}
+
+func (enc *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	n := len(enc.namespaces)
+	enc.namespaces = append(enc.namespaces, key)
+	err := obj.MarshalLogObject(enc)
+	enc.namespaces = enc.namespaces[:n]
+	return err
+}
+
+func (enc *logfmtEncoder) AddBinary(key string, val []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(val))
+}
+
+func (enc *logfmtEncoder) AddByteString(key string, val []byte) {
+	enc.AddString(key, string(val))
+}
+
+func (enc *logfmtEncoder) AddBool(key string, val bool) {
+	enc.addKey(key)
+	enc.buf.AppendBool(val)
+}
+
+func (enc *logfmtEncoder) AddComplex128(key string, val complex128) {
+	enc.addKey(key)
+	appendComplex(enc.buf, val, 64)
+}
+
+func (enc *logfmtEncoder) AddComplex64(key string, val complex64) {
+	enc.addKey(key)
+	appendComplex(enc.buf, complex128(val), 32)
+}
+
+func (enc *logfmtEncoder) AddDuration(key string, val time.Duration) {
+	enc.addKey(key)
+	enc.appendDuration(enc.buf, val, false)
+}
+
+func (enc *logfmtEncoder) AddFloat64(key string, val float64) {
+	enc.addKey(key)
+	appendFloat(enc.buf, val, 64)
+}
+
+func (enc *logfmtEncoder) AddFloat32(key string, val float32) {
+	enc.addKey(key)
+	appendFloat(enc.buf, float64(val), 32)
+}
+
+func (enc *logfmtEncoder) AddInt(key string, val int)     { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt32(key string, val int32) { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt16(key string, val int16) { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt8(key string, val int8)   { enc.AddInt64(key, int64(val)) }
+func (enc *logfmtEncoder) AddInt64(key string, val int64) {
+	enc.addKey(key)
+	enc.buf.AppendInt(val)
+}
+
+func (enc *logfmtEncoder) AddReflected(key string, val any) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	enc.addKey(key)
+	appendLogfmtString(enc.buf, string(b), false)
+	return nil
+}
+
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces, key)
+}
+
+func (enc *logfmtEncoder) AddString(key, val string) {
+	if enc.pretty != nil && strings.IndexByte(val, '\n') >= 0 {
+		enc.addTrailer(key, val)
+		return
+	}
+	enc.addKey(key)
+	appendLogfmtString(enc.buf, val, false)
+}
+
+func (enc *logfmtEncoder) AddTime(key string, val time.Time) {
+	enc.addKey(key)
+	enc.appendTime(enc.buf, val, false)
+}
+
+func (enc *logfmtEncoder) AddUint(key string, val uint)       { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint32(key string, val uint32)   { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint16(key string, val uint16)   { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint8(key string, val uint8)     { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUintptr(key string, val uintptr) { enc.AddUint64(key, uint64(val)) }
+func (enc *logfmtEncoder) AddUint64(key string, val uint64) {
+	enc.addKey(key)
+	enc.buf.AppendUint(val)
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	return enc.clone()
+}
+
+func (enc *logfmtEncoder) clone() *logfmtEncoder {
+	clone := getLogfmtEncoder(enc.EncoderConfig)
+	clone.namespaces = append(clone.namespaces, enc.namespaces...)
+	_, _ = clone.buf.Write(enc.buf.Bytes())
+	clone.pretty = enc.pretty
+	if enc.trailer != nil {
+		clone.trailer = logfmtBufferPool.Get()
+		_, _ = clone.trailer.Write(enc.trailer.Bytes())
+	}
+	return clone
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := getLogfmtEncoder(enc.EncoderConfig)
+	defer putLogfmtEncoder(final)
+
+	if final.TimeKey != "" && !ent.Time.IsZero() {
+		final.addKey(final.TimeKey)
+		final.appendTime(final.buf, ent.Time, false)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		final.addKey(final.LevelKey)
+		final.appendPrimitive(final.buf, false, func(pe zapcore.PrimitiveArrayEncoder) {
+			final.EncodeLevel(ent.Level, pe)
+		})
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addKey(final.NameKey)
+		if final.EncodeName != nil {
+			final.appendPrimitive(final.buf, false, func(pe zapcore.PrimitiveArrayEncoder) {
+				final.EncodeName(ent.LoggerName, pe)
+			})
+		} else {
+			appendLogfmtString(final.buf, ent.LoggerName, false)
+		}
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			final.addKey(final.CallerKey)
+			final.appendPrimitive(final.buf, false, func(pe zapcore.PrimitiveArrayEncoder) {
+				final.EncodeCaller(ent.Caller, pe)
+			})
+		}
+		if final.FunctionKey != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+	if enc.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		_, _ = final.buf.Write(enc.buf.Bytes())
+	}
+	final.namespaces = append(final.namespaces, enc.namespaces...)
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.namespaces = final.namespaces[:0]
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+// addKey append the key with namespaces prefix joined by dot.
+func (enc *logfmtEncoder) addKey(key string) {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	enc.appendFullKey(enc.buf, key)
+	enc.buf.AppendByte('=')
+}
+
+// appendFullKey append the key with namespaces prefix, which is colored in pretty mode.
+func (enc *logfmtEncoder) appendFullKey(buf *buffer.Buffer, key string) {
+	if enc.pretty != nil && enc.pretty.color {
+		buf.AppendString(prettyKeyColor)
+		defer buf.AppendString(prettyColorReset)
+	}
+	for _, ns := range enc.namespaces {
+		appendLogfmtKey(buf, ns)
+		buf.AppendByte('.')
+	}
+	appendLogfmtKey(buf, key)
+}
+
+// addTrailer add the multi-line value into the trailer, which is written
+// after the entry line, and every line is indented.
+func (enc *logfmtEncoder) addTrailer(key, val string) {
+	if enc.trailer == nil {
+		enc.trailer = logfmtBufferPool.Get()
+	}
+	enc.trailer.AppendString(enc.LineEnding)
+	enc.trailer.AppendString(prettyIndent)
+	enc.appendFullKey(enc.trailer, key)
+	enc.trailer.AppendByte(':')
+	for _, line := range strings.Split(strings.TrimRight(val, "\n"), "\n") {
+		enc.trailer.AppendString(enc.LineEnding)
+		enc.trailer.AppendString(prettyIndent)
+		enc.trailer.AppendString(prettyIndent)
+		enc.trailer.AppendString(line)
+	}
+}
+
+func (enc *logfmtEncoder) encodeArray(buf *buffer.Buffer, arr zapcore.ArrayMarshaler) error {
+	ae := &logfmtArrayEncoder{enc: enc, buf: buf}
+	buf.AppendByte('[')
+	err := arr.MarshalLogArray(ae)
+	buf.AppendByte(']')
+	return err
+}
+
+func (enc *logfmtEncoder) appendTime(buf *buffer.Buffer, t time.Time, strict bool) {
+	if enc.EncodeTime == nil {
+		buf.AppendInt(t.UnixNano())
+		return
+	}
+	enc.appendPrimitive(buf, strict, func(pe zapcore.PrimitiveArrayEncoder) {
+		enc.EncodeTime(t, pe)
+	})
+}
+
+func (enc *logfmtEncoder) appendDuration(buf *buffer.Buffer, d time.Duration, strict bool) {
+	if enc.EncodeDuration == nil {
+		buf.AppendInt(int64(d))
+		return
+	}
+	enc.appendPrimitive(buf, strict, func(pe zapcore.PrimitiveArrayEncoder) {
+		enc.EncodeDuration(d, pe)
+	})
+}
+
+// appendPrimitive append the value encoded by the encoder function, such as EncodeTime.
+func (enc *logfmtEncoder) appendPrimitive(buf *buffer.Buffer, strict bool, fn func(zapcore.PrimitiveArrayEncoder)) {
+	pe := &logfmtPrimitiveEncoder{buf: logfmtBufferPool.Get()}
+	defer pe.buf.Free()
+	fn(pe)
+	appendLogfmtString(buf, pe.buf.String(), strict)
+}
+
+// logfmtArrayEncoder encode the array elements separated by comma,
+// the object element is rendered as {k=v ...}.
+type logfmtArrayEncoder struct {
+	enc *logfmtEncoder
+	buf *buffer.Buffer
+}
+
+func (a *logfmtArrayEncoder) separate() {
+	if last := a.buf.Len(); last > 0 && a.buf.Bytes()[last-1] != '[' {
+		a.buf.AppendByte(',')
+	}
+}
+
+func (a *logfmtArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	a.separate()
+	return a.enc.encodeArray(a.buf, arr)
+}
+
+func (a *logfmtArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	a.separate()
+	sub := getLogfmtEncoder(a.enc.EncoderConfig)
+	defer func() {
+		sub.buf.Free()
+		putLogfmtEncoder(sub)
+	}()
+	err := obj.MarshalLogObject(sub)
+	a.buf.AppendByte('{')
+	_, _ = a.buf.Write(sub.buf.Bytes())
+	a.buf.AppendByte('}')
+	return err
+}
+
+func (a *logfmtArrayEncoder) AppendReflected(val any) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	a.separate()
+	appendLogfmtString(a.buf, string(b), true)
+	return nil
+}
+
+func (a *logfmtArrayEncoder) AppendBool(v bool) {
+	a.separate()
+	a.buf.AppendBool(v)
+}
+
+func (a *logfmtArrayEncoder) AppendByteString(v []byte) { a.AppendString(string(v)) }
+
+func (a *logfmtArrayEncoder) AppendComplex128(v complex128) {
+	a.separate()
+	appendComplex(a.buf, v, 64)
+}
+
+func (a *logfmtArrayEncoder) AppendComplex64(v complex64) {
+	a.separate()
+	appendComplex(a.buf, complex128(v), 32)
+}
+
+func (a *logfmtArrayEncoder) AppendFloat64(v float64) {
+	a.separate()
+	appendFloat(a.buf, v, 64)
+}
+
+func (a *logfmtArrayEncoder) AppendFloat32(v float32) {
+	a.separate()
+	appendFloat(a.buf, float64(v), 32)
+}
+
+func (a *logfmtArrayEncoder) AppendInt(v int)     { a.AppendInt64(int64(v)) }
+func (a *logfmtArrayEncoder) AppendInt32(v int32) { a.AppendInt64(int64(v)) }
+func (a *logfmtArrayEncoder) AppendInt16(v int16) { a.AppendInt64(int64(v)) }
+func (a *logfmtArrayEncoder) AppendInt8(v int8)   { a.AppendInt64(int64(v)) }
+func (a *logfmtArrayEncoder) AppendInt64(v int64) {
+	a.separate()
+	a.buf.AppendInt(v)
+}
+
+func (a *logfmtArrayEncoder) AppendString(v string) {
+	a.separate()
+	appendLogfmtString(a.buf, v, true)
+}
+
+func (a *logfmtArrayEncoder) AppendUint(v uint)       { a.AppendUint64(uint64(v)) }
+func (a *logfmtArrayEncoder) AppendUint32(v uint32)   { a.AppendUint64(uint64(v)) }
+func (a *logfmtArrayEncoder) AppendUint16(v uint16)   { a.AppendUint64(uint64(v)) }
+func (a *logfmtArrayEncoder) AppendUint8(v uint8)     { a.AppendUint64(uint64(v)) }
+func (a *logfmtArrayEncoder) AppendUintptr(v uintptr) { a.AppendUint64(uint64(v)) }
+func (a *logfmtArrayEncoder) AppendUint64(v uint64) {
+	a.separate()
+	a.buf.AppendUint(v)
+}
+
+func (a *logfmtArrayEncoder) AppendDuration(v time.Duration) {
+	a.separate()
+	a.enc.appendDuration(a.buf, v, true)
+}
+
+func (a *logfmtArrayEncoder) AppendTime(v time.Time) {
+	a.separate()
+	a.enc.appendTime(a.buf, v, true)
+}
+
+// logfmtPrimitiveEncoder collect the raw values appended by
+// the encoder function of EncoderConfig, such as EncodeTime.
+type logfmtPrimitiveEncoder struct {
+	buf *buffer.Buffer
+}
+
+func (p *logfmtPrimitiveEncoder) separate() {
+	if p.buf.Len() > 0 {
+		p.buf.AppendByte(',')
+	}
+}
+
+func (p *logfmtPrimitiveEncoder) AppendBool(v bool) {
+	p.separate()
+	p.buf.AppendBool(v)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendByteString(v []byte) {
+	p.separate()
+	_, _ = p.buf.Write(v)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendComplex128(v complex128) {
+	p.separate()
+	appendComplex(p.buf, v, 64)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendComplex64(v complex64) {
+	p.separate()
+	appendComplex(p.buf, complex128(v), 32)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendFloat64(v float64) {
+	p.separate()
+	appendFloat(p.buf, v, 64)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendFloat32(v float32) {
+	p.separate()
+	appendFloat(p.buf, float64(v), 32)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendInt(v int)     { p.AppendInt64(int64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendInt32(v int32) { p.AppendInt64(int64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendInt16(v int16) { p.AppendInt64(int64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendInt8(v int8)   { p.AppendInt64(int64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendInt64(v int64) {
+	p.separate()
+	p.buf.AppendInt(v)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendString(v string) {
+	p.separate()
+	p.buf.AppendString(v)
+}
+
+func (p *logfmtPrimitiveEncoder) AppendUint(v uint)       { p.AppendUint64(uint64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendUint32(v uint32)   { p.AppendUint64(uint64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendUint16(v uint16)   { p.AppendUint64(uint64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendUint8(v uint8)     { p.AppendUint64(uint64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendUintptr(v uintptr) { p.AppendUint64(uint64(v)) }
+func (p *logfmtPrimitiveEncoder) AppendUint64(v uint64) {
+	p.separate()
+	p.buf.AppendUint(v)
+}
+
+// appendLogfmtKey append the key, the characters which is invalid
+// in logfmt key are replaced with '_'.
+func appendLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	for i, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			buf.AppendByte('_')
+		} else {
+			buf.AppendString(key[i : i+utf8.RuneLen(r)])
+		}
+	}
+}
+
+// appendLogfmtString append the value, quote and escape it if needed.
+// strict is used for array element, which need quote if contains ,[]{} also.
+func appendLogfmtString(buf *buffer.Buffer, s string, strict bool) {
+	if !needsQuote(s, strict) {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendByte('"')
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			switch b {
+			case '"', '\\':
+				buf.AppendByte('\\')
+				buf.AppendByte(b)
+			case '\n':
+				buf.AppendString(`\n`)
+			case '\r':
+				buf.AppendString(`\r`)
+			case '\t':
+				buf.AppendString(`\t`)
+			default:
+				if b < 0x20 || b == 0x7f {
+					buf.AppendString(`\u00`)
+					buf.AppendByte(logfmtHex[b>>4])
+					buf.AppendByte(logfmtHex[b&0xF])
+				} else {
+					buf.AppendByte(b)
+				}
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.AppendString("\ufffd")
+		} else {
+			buf.AppendString(s[i : i+size])
+		}
+		i += size
+	}
+	buf.AppendByte('"')
+}
+
+func needsQuote(s string, strict bool) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b <= ' ' || b == '=' || b == '"' || b == '\\' || b == 0x7f {
+				return true
+			}
+			if strict && (b == ',' || b == '[' || b == ']' || b == '{' || b == '}') {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+func appendFloat(buf *buffer.Buffer, v float64, bitSize int) {
+	switch {
+	case math.IsNaN(v):
+		buf.AppendString("NaN")
+	case math.IsInf(v, 1):
+		buf.AppendString("+Inf")
+	case math.IsInf(v, -1):
+		buf.AppendString("-Inf")
+	default:
+		buf.AppendFloat(v, bitSize)
+	}
+}
+
+func appendComplex(buf *buffer.Buffer, v complex128, precision int) {
+	r, i := real(v), imag(v)
+	buf.AppendFloat(r, precision)
+	if i >= 0 {
+		buf.AppendByte('+')
+	}
+	buf.AppendFloat(i, precision)
+	buf.AppendByte('i')
+}
@@ -0,0 +1,64 @@
+package log_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func (u testUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddInt("age", u.Age)
+	return nil
+}
+
+func Test_LogfmtEncoder(t *testing.T) {
+	l, buf := newBufferLogger(log.WithFormat(log.FormatLogfmt))
+	l.With(log.String("with", "w")).
+		Named("svc").
+		Infox("hello world",
+			log.String("plain", "v"),
+			log.String("quote", `say "hi"`),
+			log.String("empty", ""),
+			log.String("newline", "a\nb"),
+			log.Int("int", 1),
+			log.Bool("bool", true),
+			log.Duration("cost", time.Second),
+			log.Err(errors.New("oops")),
+			log.Object("user", testUser{"jack", 18}),
+			zap.Strings("tags", []string{"a", "b c"}),
+			zap.Ints("ids", []int{1, 2}),
+			log.Namespace("ns"),
+			log.String("k", "v"),
+		)
+	got := buf.String()
+	for _, want := range []string{
+		`level=info logger=svc msg="hello world" with=w`,
+		` plain=v `,
+		` quote="say \"hi\"" `,
+		` empty="" `,
+		` newline="a\nb" `,
+		` int=1 bool=true cost=1s error=oops `,
+		` user.name=jack user.age=18 `,
+		` tags="[a,\"b c\"]" `,
+		` ids=[1,2] `,
+		` ns.k=v` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want contains %s, got: %s", want, got)
+		}
+	}
+	if !strings.HasPrefix(got, "ts=") {
+		t.Errorf("want prefix ts=, got: %s", got)
+	}
+}
@@ -0,0 +1,205 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	prettyColorReset = "\x1b[0m"
+	prettyKeyColor   = "\x1b[36m" // cyan
+	prettyDimColor   = "\x1b[90m" // gray
+	prettyIndent     = "    "
+
+	prettyCallerWidth  = 24
+	prettyMessageWidth = 40
+)
+
+type prettyConfig struct {
+	color bool
+	start time.Time
+}
+
+// prettyEncoder encode the entry for human in development, such as:
+//
+//	 +1.234s INFO   svc main.go:12  hello world                  key=val
+//	    errorVerbose:
+//	        ...
+//
+// columns are aligned, levels and keys are colored, fields are key=value,
+// the multi-line values such as stack traces and error chains are indented
+// under the entry line, timestamps are relative to the process start.
+type prettyEncoder struct {
+	*logfmtEncoder
+}
+
+// NewPrettyEncoder creates a pretty encoder,
+// color enables the color of level and key.
+func NewPrettyEncoder(cfg zapcore.EncoderConfig, color bool) zapcore.Encoder {
+	if cfg.LineEnding == "" {
+		cfg.LineEnding = zapcore.DefaultLineEnding
+	}
+	return &prettyEncoder{
+		logfmtEncoder: &logfmtEncoder{
+			EncoderConfig: &cfg,
+			buf:           logfmtBufferPool.Get(),
+			pretty:        &prettyConfig{color: color, start: processStartTime},
+		},
+	}
+}
+
+// ColorEnabled reports whether the color should be used for stdout,
+// it is disabled when stdout is not a terminal or NO_COLOR is set.
+func ColorEnabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (enc *prettyEncoder) Clone() zapcore.Encoder {
+	return &prettyEncoder{logfmtEncoder: enc.logfmtEncoder.clone()}
+}
+
+func (enc *prettyEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	color := enc.pretty.color
+	line := logfmtBufferPool.Get()
+
+	if enc.TimeKey != "" && !ent.Time.IsZero() {
+		if color {
+			line.AppendString(prettyDimColor)
+		}
+		appendPadLeft(line, "+"+formatElapsed(ent.Time.Sub(enc.pretty.start)), 9)
+		if color {
+			line.AppendString(prettyColorReset)
+		}
+		line.AppendByte(' ')
+	}
+	if enc.LevelKey != "" {
+		if color {
+			line.AppendString(prettyLevelColor(ent.Level))
+		}
+		appendPadRight(line, ent.Level.CapitalString(), 6)
+		if color {
+			line.AppendString(prettyColorReset)
+		}
+		line.AppendByte(' ')
+	}
+	if ent.LoggerName != "" && enc.NameKey != "" {
+		line.AppendString(ent.LoggerName)
+		line.AppendByte(' ')
+	}
+	if ent.Caller.Defined && enc.CallerKey != "" && enc.EncodeCaller != nil {
+		caller := logfmtBufferPool.Get()
+		enc.appendPrimitive(caller, false, func(pe zapcore.PrimitiveArrayEncoder) {
+			enc.EncodeCaller(ent.Caller, pe)
+		})
+		if color {
+			line.AppendString(prettyDimColor)
+		}
+		appendPadRight(line, caller.String(), prettyCallerWidth)
+		if color {
+			line.AppendString(prettyColorReset)
+		}
+		line.AppendByte(' ')
+		caller.Free()
+	}
+
+	final := enc.logfmtEncoder.clone()
+	defer putLogfmtEncoder(final)
+	for i := range fields {
+		addPrettyField(final, fields[i])
+	}
+	if ent.Stack != "" && enc.StacktraceKey != "" {
+		final.addTrailer(enc.StacktraceKey, ent.Stack)
+	}
+
+	if enc.MessageKey != "" {
+		if final.buf.Len() > 0 {
+			appendPadRight(line, ent.Message, prettyMessageWidth)
+			line.AppendByte(' ')
+		} else {
+			line.AppendString(ent.Message)
+		}
+	}
+	_, _ = line.Write(final.buf.Bytes())
+	final.buf.Free()
+	if final.trailer != nil {
+		_, _ = line.Write(final.trailer.Bytes())
+		final.trailer.Free()
+	}
+	line.AppendString(enc.LineEnding)
+	return line, nil
+}
+
+// addPrettyField add the field, the error chain is added into the trailer.
+func addPrettyField(enc *logfmtEncoder, f zapcore.Field) {
+	if f.Type != zapcore.ErrorType {
+		f.AddTo(enc)
+		return
+	}
+	err, ok := f.Interface.(error)
+	if !ok || err == nil {
+		f.AddTo(enc)
+		return
+	}
+	enc.AddString(f.Key, err.Error())
+	if _, ok := err.(fmt.Formatter); ok {
+		enc.addTrailer(f.Key+"Verbose", fmt.Sprintf("%+v", err))
+		return
+	}
+	if cause := errors.Unwrap(err); cause != nil {
+		chain := logfmtBufferPool.Get()
+		defer chain.Free()
+		for ; cause != nil; cause = errors.Unwrap(cause) {
+			if chain.Len() > 0 {
+				chain.AppendByte('\n')
+			}
+			chain.AppendString("caused by: ")
+			chain.AppendString(cause.Error())
+		}
+		enc.addTrailer(f.Key+"Chain", chain.String())
+	}
+}
+
+func prettyLevelColor(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return "\x1b[35m" // magenta
+	case zapcore.InfoLevel:
+		return "\x1b[34m" // blue
+	case zapcore.WarnLevel:
+		return "\x1b[33m" // yellow
+	default:
+		return "\x1b[31m" // red
+	}
+}
+
+// formatElapsed format the elapsed duration in seconds with millisecond precision.
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+func appendPadRight(buf *buffer.Buffer, s string, width int) {
+	buf.AppendString(s)
+	for n := utf8.RuneCountInString(s); n < width; n++ {
+		buf.AppendByte(' ')
+	}
+}
+
+func appendPadLeft(buf *buffer.Buffer, s string, width int) {
+	for n := utf8.RuneCountInString(s); n < width; n++ {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(s)
+}
@@ -0,0 +1,34 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/things-go/log"
+)
+
+func Test_PrettyEncoder(t *testing.T) {
+	l, buf := newBufferLogger(log.WithFormat(log.FormatPretty), log.WithAddCaller(true))
+	err := fmt.Errorf("query: %w", errors.New("connection refused"))
+	l.Named("svc").
+		With(log.String("with", "w")).
+		Errorx("hello world", log.Int("int", 1), log.Err(err))
+	got := buf.String()
+	lines := strings.Split(strings.TrimRight(got, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want 3 lines, got: %q", got)
+	}
+	for _, want := range []string{"ERROR  svc ", "hello world", " with=w int=1 error=\"query: connection refused\""} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("want contains %s, got: %s", want, lines[0])
+		}
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("color should be disabled for custom adapter, got: %q", got)
+	}
+	if lines[1] != "    errorChain:" || lines[2] != "        caused by: connection refused" {
+		t.Errorf("want indented error chain, got: %q", lines[1:])
+	}
+}
@@ -0,0 +1,302 @@
+package log
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// profile defined
+const (
+	ProfileECS     = "ecs"     // Elastic Common Schema
+	ProfileGCP     = "gcp"     // Google Cloud Logging
+	ProfileDatadog = "datadog" // Datadog
+)
+
+// ECSVersion the version of Elastic Common Schema.
+const ECSVersion = "8.11.0"
+
+// profile the encoder profile for the ingest schema.
+type profile struct {
+	// encoderConfig the encoder config, which replaces the default one.
+	encoderConfig zapcore.EncoderConfig
+	// fields the static fields, which are added once.
+	fields []Field
+	// rewrite rewrite the field and append to fields.
+	rewrite func(fields []Field, f Field) []Field
+	// entryFields append the fields derived from the entry, such as caller.
+	entryFields func(fields []Field, ent zapcore.Entry) []Field
+}
+
+func toProfile(name string) *profile {
+	switch name {
+	case ProfileECS:
+		return ecsProfile()
+	case ProfileGCP:
+		return gcpProfile()
+	case ProfileDatadog:
+		return datadogProfile()
+	default:
+		return nil
+	}
+}
+
+// ecsProfile see https://www.elastic.co/guide/en/ecs/current/ecs-field-reference.html
+func ecsProfile() *profile {
+	renames := map[string]string{
+		"traceId":   "trace.id",
+		"spanId":    "span.id",
+		"requestId": "http.request.id",
+	}
+	return &profile{
+		encoderConfig: zapcore.EncoderConfig{
+			TimeKey:        "@timestamp",
+			LevelKey:       "log.level",
+			NameKey:        "log.logger",
+			CallerKey:      zapcore.OmitKey,
+			FunctionKey:    zapcore.OmitKey,
+			MessageKey:     "message",
+			StacktraceKey:  "error.stack_trace",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    zapcore.LowercaseLevelEncoder,
+			EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+			EncodeDuration: zapcore.NanosDurationEncoder,
+		},
+		fields: []Field{zap.String("ecs.version", ECSVersion)},
+		rewrite: func(fields []Field, f Field) []Field {
+			if isErrorField(f) {
+				err := f.Interface.(error)
+				fields = append(fields,
+					zap.String("error.message", err.Error()),
+					zap.String("error.type", fmt.Sprintf("%T", err)),
+				)
+				if _, ok := err.(fmt.Formatter); ok {
+					fields = append(fields, zap.String("error.stack_trace", fmt.Sprintf("%+v", err)))
+				}
+				return fields
+			}
+			return append(fields, renameField(f, renames))
+		},
+		entryFields: func(fields []Field, ent zapcore.Entry) []Field {
+			if !ent.Caller.Defined {
+				return fields
+			}
+			return append(fields,
+				zap.String("log.origin.file.name", ent.Caller.File),
+				zap.Int("log.origin.file.line", ent.Caller.Line),
+				zap.String("log.origin.function", ent.Caller.Function),
+			)
+		},
+	}
+}
+
+// gcpProfile see https://cloud.google.com/logging/docs/structured-logging
+// the project for trace is from environment GOOGLE_CLOUD_PROJECT.
+func gcpProfile() *profile {
+	project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	return &profile{
+		encoderConfig: zapcore.EncoderConfig{
+			TimeKey:        "timestamp",
+			LevelKey:       "severity",
+			NameKey:        "logger",
+			CallerKey:      zapcore.OmitKey,
+			FunctionKey:    zapcore.OmitKey,
+			MessageKey:     "message",
+			StacktraceKey:  "stack_trace",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    gcpLevelEncoder,
+			EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+		},
+		rewrite: func(fields []Field, f Field) []Field {
+			switch {
+			case f.Key == "traceId" && f.Type == zapcore.StringType:
+				trace := f.String
+				if project != "" {
+					trace = "projects/" + project + "/traces/" + trace
+				}
+				return append(fields, zap.String("logging.googleapis.com/trace", trace))
+			case f.Key == "spanId":
+				f.Key = "logging.googleapis.com/spanId"
+			}
+			return append(fields, f)
+		},
+		entryFields: func(fields []Field, ent zapcore.Entry) []Field {
+			if !ent.Caller.Defined {
+				return fields
+			}
+			return append(fields, zap.Object("logging.googleapis.com/sourceLocation", gcpSourceLocation(ent.Caller)))
+		},
+	}
+}
+
+// datadogProfile see https://docs.datadoghq.com/logs/log_configuration/attributes_naming_convention
+func datadogProfile() *profile {
+	renames := map[string]string{
+		"traceId": "dd.trace_id",
+		"spanId":  "dd.span_id",
+	}
+	return &profile{
+		encoderConfig: zapcore.EncoderConfig{
+			TimeKey:        "timestamp",
+			LevelKey:       "status",
+			NameKey:        "logger.name",
+			CallerKey:      "logger.caller", // file:line, no standard attribute
+			FunctionKey:    "logger.method_name",
+			MessageKey:     "message",
+			StacktraceKey:  "error.stack",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    zapcore.LowercaseLevelEncoder,
+			EncodeTime:     zapcore.EpochMillisTimeEncoder,
+			EncodeDuration: zapcore.NanosDurationEncoder,
+			EncodeCaller:   zapcore.ShortCallerEncoder,
+		},
+		rewrite: func(fields []Field, f Field) []Field {
+			if isErrorField(f) {
+				err := f.Interface.(error)
+				fields = append(fields,
+					zap.String("error.message", err.Error()),
+					zap.String("error.kind", fmt.Sprintf("%T", err)),
+				)
+				if _, ok := err.(fmt.Formatter); ok {
+					fields = append(fields, zap.String("error.stack", fmt.Sprintf("%+v", err)))
+				}
+				return fields
+			}
+			return append(fields, renameField(f, renames))
+		},
+	}
+}
+
+// gcpLevelEncoder encode level to the severity of Google Cloud Logging.
+func gcpLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch l {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.PanicLevel:
+		enc.AppendString("ALERT")
+	case zapcore.FatalLevel:
+		enc.AppendString("EMERGENCY")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+type gcpSourceLocation zapcore.EntryCaller
+
+func (c gcpSourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", c.File)
+	enc.AddString("line", fmt.Sprint(c.Line))
+	enc.AddString("function", c.Function)
+	return nil
+}
+
+func isErrorField(f Field) bool {
+	return f.Key == "error" && f.Type == zapcore.ErrorType
+}
+
+func renameField(f Field, renames map[string]string) Field {
+	if key, ok := renames[f.Key]; ok {
+		f.Key = key
+	}
+	return f
+}
+
+// profileCore is a zapcore.Core that rewrites the fields with the profile.
+// the stack trace of the error field, such as error.stack_trace of ECS, has the same key
+// as the stack trace of the entry, only one of them is written.
+type profileCore struct {
+	core    zapcore.Core
+	profile *profile
+	stack   bool // the With fields have the stack trace key
+	nested  bool // the With fields have opened a namespace
+}
+
+func newProfileCore(core zapcore.Core, p *profile) zapcore.Core {
+	if p == nil {
+		return core
+	}
+	pc := &profileCore{core: core, profile: p}
+	if len(p.fields) > 0 {
+		return pc.With(p.fields)
+	}
+	return pc
+}
+
+func (c *profileCore) Enabled(lvl zapcore.Level) bool { return c.core.Enabled(lvl) }
+
+func (c *profileCore) With(fields []Field) zapcore.Core {
+	fs := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		fs = c.profile.rewrite(fs, f)
+	}
+	clone := &profileCore{
+		core:    c.core.With(fs),
+		profile: c.profile,
+		stack:   c.stack,
+		nested:  c.nested,
+	}
+	for _, f := range fs {
+		if f.Type == zapcore.NamespaceType {
+			clone.nested = true
+		} else if !clone.nested && f.Key == c.profile.encoderConfig.StacktraceKey {
+			clone.stack = true
+		}
+	}
+	return clone
+}
+
+func (c *profileCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *profileCore) Write(ent zapcore.Entry, fields []Field) error {
+	fc := poolGet()
+	defer poolPut(fc)
+	if c.profile.entryFields != nil {
+		fc.Fields = c.profile.entryFields(fc.Fields, ent)
+	}
+	for _, f := range fields {
+		fc.Fields = c.profile.rewrite(fc.Fields, f)
+	}
+	if ent.Stack != "" {
+		if c.stack {
+			// the stack trace of the error is encoded by With already.
+			ent.Stack = ""
+		} else if !c.nested {
+			fc.Fields = c.dropStack(fc.Fields)
+		}
+	}
+	return c.core.Write(ent, fc.Fields)
+}
+
+// dropStack removes the stack trace of the error fields, which is out of any namespace,
+// the stack trace of the entry is written instead.
+func (c *profileCore) dropStack(fields []Field) []Field {
+	out := fields[:0]
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return append(out, fields[i:]...)
+		}
+		if f.Key != c.profile.encoderConfig.StacktraceKey {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (c *profileCore) Sync() error     { return c.core.Sync() }
+func (c *profileCore) recording() bool { return isRecording(c.core) }
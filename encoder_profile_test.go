@@ -0,0 +1,110 @@
+package log_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/things-go/log"
+)
+
+func Test_EncoderProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    []string
+	}{
+		{
+			"ecs",
+			log.ProfileECS,
+			[]string{
+				`"log.level":"error"`,
+				`"@timestamp":`,
+				`"message":"profile"`,
+				`"ecs.version":"` + log.ECSVersion + `"`,
+				`"log.origin.file.name":`,
+				`"trace.id":"abc"`,
+				`"error.message":"oops"`,
+				`"error.type":"*errors.errorString"`,
+			},
+		},
+		{
+			"gcp",
+			log.ProfileGCP,
+			[]string{
+				`"severity":"ERROR"`,
+				`"timestamp":`,
+				`"message":"profile"`,
+				`"logging.googleapis.com/trace":"projects/demo/traces/abc"`,
+				`"logging.googleapis.com/sourceLocation":{"file":`,
+				`"error":"oops"`,
+			},
+		},
+		{
+			"datadog",
+			log.ProfileDatadog,
+			[]string{
+				`"status":"error"`,
+				`"message":"profile"`,
+				`"logger.method_name":"github.com/things-go/log_test.Test_EncoderProfile.func1"`,
+				`"logger.caller":"`, `/encoder_profile_test.go:`,
+				`"dd.trace_id":"abc"`,
+				`"error.message":"oops"`,
+				`"error.kind":"*errors.errorString"`,
+			},
+		},
+	}
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "demo")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, buf := newBufferLogger(log.WithProfile(tt.profile), log.WithAddCaller(true), log.WithCallerSkip(3))
+			l.With(log.String("traceId", "abc")).
+				Errorx("profile", log.Err(errors.New("oops")))
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("want contains %s, got: %s", want, got)
+				}
+			}
+		})
+	}
+}
+
+// stackError formats the stack trace with %+v, such as github.com/pkg/errors.
+type stackError struct{}
+
+func (stackError) Error() string { return "oops" }
+func (e stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		_, _ = io.WriteString(s, "oops\nerror stack")
+		return
+	}
+	_, _ = io.WriteString(s, e.Error())
+}
+
+func Test_EncoderProfile_StackTrace(t *testing.T) {
+	for _, profile := range []string{log.ProfileECS, log.ProfileDatadog} {
+		t.Run(profile, func(t *testing.T) {
+			l, buf := newBufferLogger(log.WithProfile(profile), log.WithStack(true))
+			stackKey := `"error.stack_trace":`
+			if profile == log.ProfileDatadog {
+				stackKey = `"error.stack":`
+			}
+
+			l.DPanicx("call", log.Err(stackError{}))
+			got := buf.String()
+			if strings.Count(got, stackKey) != 1 || strings.Contains(got, "error stack") {
+				t.Errorf("want only the stack trace of the entry, got: %s", got)
+			}
+
+			buf.Reset()
+			l.With(log.Err(stackError{})).DPanicx("with")
+			got = buf.String()
+			if strings.Count(got, stackKey) != 1 || !strings.Contains(got, "error stack") {
+				t.Errorf("want only the stack trace of the error, got: %s", got)
+			}
+		})
+	}
+}
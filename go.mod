@@ -3,9 +3,11 @@ module github.com/things-go/log
 go 1.21
 
 require (
+	github.com/klauspost/compress v1.17.10
 	github.com/natefinch/lumberjack v2.0.0+incompatible
 	go.uber.org/multierr v1.11.0
 	go.uber.org/zap v1.27.0
+	golang.org/x/sys v0.21.0
 )
 
 require (
@@ -0,0 +1,138 @@
+// Package gormlog provides the logger.Interface of GORM backed by *log.Log.
+package gormlog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+
+	"github.com/things-go/log"
+)
+
+var (
+	_ logger.Interface  = (*Logger)(nil)
+	_ gorm.ParamsFilter = (*Logger)(nil)
+)
+
+// Option the option of the logger.
+type Option func(l *Logger)
+
+// WithLogLevel the log level of GORM, default logger.Warn.
+func WithLogLevel(lv logger.LogLevel) Option {
+	return func(l *Logger) { l.logLevel = lv }
+}
+
+// WithSlowThreshold the query slower than the threshold is logged in warn level, default 200ms, 0 disable.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(l *Logger) { l.slowThreshold = threshold }
+}
+
+// WithIgnoreRecordNotFound the gorm.ErrRecordNotFound is not logged as error.
+func WithIgnoreRecordNotFound() Option {
+	return func(l *Logger) { l.ignoreRecordNotFound = true }
+}
+
+// WithParameterizedQueries the sql is logged with the placeholders instead of the interpolated values,
+// which keeps the sensitive values out of the logs.
+func WithParameterizedQueries() Option {
+	return func(l *Logger) { l.parameterizedQueries = true }
+}
+
+// Logger implements logger.Interface of GORM, the entries are logged with the context of
+// the statement, so the Valuers see the trace id of the caller.
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{
+//		Logger: gormlog.New(l.Named("gorm"), gormlog.WithSlowThreshold(time.Second)),
+//	})
+type Logger struct {
+	log                  *log.Log
+	logLevel             logger.LogLevel
+	slowThreshold        time.Duration
+	ignoreRecordNotFound bool
+	parameterizedQueries bool
+}
+
+// New returns the GORM logger.
+func New(l *log.Log, opts ...Option) *Logger {
+	g := &Logger{
+		log:           l,
+		logLevel:      logger.Warn,
+		slowThreshold: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// LogMode implements logger.Interface.
+func (g *Logger) LogMode(lv logger.LogLevel) logger.Interface {
+	clone := *g
+	clone.logLevel = lv
+	return &clone
+}
+
+// Info implements logger.Interface.
+func (g *Logger) Info(ctx context.Context, msg string, data ...any) {
+	if g.logLevel >= logger.Info {
+		g.log.Logf(ctx, log.InfoLevel, msg, data...)
+	}
+}
+
+// Warn implements logger.Interface.
+func (g *Logger) Warn(ctx context.Context, msg string, data ...any) {
+	if g.logLevel >= logger.Warn {
+		g.log.Logf(ctx, log.WarnLevel, msg, data...)
+	}
+}
+
+// Error implements logger.Interface.
+func (g *Logger) Error(ctx context.Context, msg string, data ...any) {
+	if g.logLevel >= logger.Error {
+		g.log.Logf(ctx, log.ErrorLevel, msg, data...)
+	}
+}
+
+// Trace implements logger.Interface, it logs the failed query in error level,
+// the slow query in warn level, and others in info level if the log level is logger.Info.
+func (g *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.logLevel <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && g.logLevel >= logger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !g.ignoreRecordNotFound):
+		g.log.Logx(ctx, log.ErrorLevel, "gorm query", append(traceFields(fc, elapsed), log.Err(err))...)
+	case g.slowThreshold != 0 && elapsed > g.slowThreshold && g.logLevel >= logger.Warn:
+		g.log.Logx(ctx, log.WarnLevel, "gorm slow query",
+			append(traceFields(fc, elapsed), log.Duration("slowThreshold", g.slowThreshold))...)
+	case g.logLevel == logger.Info:
+		g.log.Logx(ctx, log.InfoLevel, "gorm query", traceFields(fc, elapsed)...)
+	}
+}
+
+// ParamsFilter implements gorm.ParamsFilter, the params are dropped if parameterized queries.
+func (g *Logger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if g.parameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func traceFields(fc func() (string, int64), elapsed time.Duration) []log.Field {
+	sql, rows := fc()
+	fields := make([]log.Field, 0, 5)
+	fields = append(fields,
+		log.String("sql", sql),
+		log.Duration("latency", elapsed),
+		log.String("source", utils.FileWithLineNum()),
+	)
+	if rows >= 0 { // -1 means unknown
+		fields = append(fields, log.Int64("rows", rows))
+	}
+	return fields
+}
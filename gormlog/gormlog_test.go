@@ -0,0 +1,105 @@
+package gormlog_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/gormlog"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func query(sql string, rows int64) func() (string, int64) {
+	return func() (string, int64) { return sql, rows }
+}
+
+func Test_Trace(t *testing.T) {
+	l, buf := newBufferLogger()
+	g := gormlog.New(l, gormlog.WithSlowThreshold(time.Second))
+	ctx := context.Background()
+
+	// the default log level is warn.
+	g.Trace(ctx, time.Now(), query("SELECT 1", 1), nil)
+	if buf.Len() != 0 {
+		t.Fatalf("want nothing logged, got: %s", buf.String())
+	}
+
+	g.Trace(ctx, time.Now().Add(-2*time.Second), query("SELECT * FROM users", -1), nil)
+	assertContains(t, buf.String(), "level=warn", `msg="gorm slow query"`, `sql="SELECT * FROM users"`, "slowThreshold=1s", "source=")
+	if strings.Contains(buf.String(), "rows=") {
+		t.Errorf("want unknown rows omitted, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	g.Trace(ctx, time.Now(), query("SELECT * FROM users", 0), errors.New("bad connection"))
+	assertContains(t, buf.String(), "level=error", `error="bad connection"`, "rows=0")
+
+	buf.Reset()
+	g.LogMode(logger.Info).Trace(ctx, time.Now(), query("SELECT 1", 1), nil)
+	assertContains(t, buf.String(), "level=info", `msg="gorm query"`, "rows=1")
+
+	buf.Reset()
+	g.LogMode(logger.Silent).Trace(ctx, time.Now(), query("SELECT 1", 1), errors.New("ignored"))
+	if buf.Len() != 0 {
+		t.Errorf("want silent, got: %s", buf.String())
+	}
+}
+
+func Test_Trace_RecordNotFound(t *testing.T) {
+	l, buf := newBufferLogger()
+	g := gormlog.New(l)
+	g.Trace(context.Background(), time.Now(), query("SELECT 1", 0), gorm.ErrRecordNotFound)
+	assertContains(t, buf.String(), "level=error", `error="record not found"`)
+
+	buf.Reset()
+	g = gormlog.New(l, gormlog.WithIgnoreRecordNotFound())
+	g.Trace(context.Background(), time.Now(), query("SELECT 1", 0), gorm.ErrRecordNotFound)
+	if buf.Len() != 0 {
+		t.Errorf("want record not found ignored, got: %s", buf.String())
+	}
+}
+
+func Test_ParamsFilter(t *testing.T) {
+	l, _ := newBufferLogger()
+	sql, params := gormlog.New(l).ParamsFilter(context.Background(), "SELECT ?", 1)
+	if sql != "SELECT ?" || len(params) != 1 {
+		t.Errorf("want params kept, got: %s %v", sql, params)
+	}
+	_, params = gormlog.New(l, gormlog.WithParameterizedQueries()).ParamsFilter(context.Background(), "SELECT ?", 1)
+	if params != nil {
+		t.Errorf("want params dropped, got: %v", params)
+	}
+}
+
+func Test_Info(t *testing.T) {
+	l, buf := newBufferLogger()
+	g := gormlog.New(l).LogMode(logger.Info)
+	g.Info(context.Background(), "migrate %s", "users")
+	g.Warn(context.Background(), "deprecated")
+	assertContains(t, buf.String(), `level=info msg="migrate users"`, "level=warn msg=deprecated")
+}
@@ -0,0 +1,305 @@
+// Package grpclog provides the gRPC interceptors for logging unary and streaming calls,
+// and the adapter which routes the internal logs of grpc-go through *log.Log.
+package grpclog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/things-go/log"
+)
+
+// default metadata keys of the ids
+const (
+	DefaultRequestIdKey = "x-request-id"
+	DefaultTraceIdKey   = "x-trace-id"
+	// TraceParentKey the W3C trace context, the trace id is parsed from it if the trace id key is absent.
+	TraceParentKey = "traceparent"
+)
+
+// Redacted the value of the redacted string fields.
+const Redacted = "[REDACTED]"
+
+type requestIdKey struct{}
+type traceIdKey struct{}
+type loggerKey struct{}
+
+// ContextWithRequestId returns a context which carries the request id.
+func ContextWithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestIdFromContext returns the request id, which is used with the RequestId preset
+// by the loggers not from FromContext, such as the global logger:
+//
+//	log.SetDefaultValuer(log.RequestId(grpclog.RequestIdFromContext))
+//
+// the logger passed to the server interceptors should not have the preset, the request-scoped
+// logger already has the requestId field.
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// ContextWithTraceId returns a context which carries the trace id.
+func ContextWithTraceId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIdKey{}, id)
+}
+
+// TraceIdFromContext returns the trace id, which is used with the TraceId preset:
+//
+//	l.SetDefaultValuer(log.TraceId(grpclog.TraceIdFromContext))
+func TraceIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIdKey{}).(string)
+	return id
+}
+
+// FromContext returns the request-scoped logger stored by the server interceptors,
+// which has the requestId field, it returns nil if not found.
+func FromContext(ctx context.Context) *log.Log {
+	l, _ := ctx.Value(loggerKey{}).(*log.Log)
+	return l
+}
+
+// Option the option of the interceptors.
+type Option func(c *config)
+
+type config struct {
+	serverMessage string
+	clientMessage string
+	requestIdKey  string
+	traceIdKey    string
+	genRequestId  func() string
+	level         func(code codes.Code) log.Level
+	skipMethods   map[string]struct{}
+	payload       int
+	redact        map[protoreflect.Name]struct{}
+	redactor      func(fullMethod string, msg any) any
+}
+
+func newConfig(level func(code codes.Code) log.Level, opts []Option) *config {
+	c := &config{
+		serverMessage: "grpc server",
+		clientMessage: "grpc client",
+		requestIdKey:  DefaultRequestIdKey,
+		traceIdKey:    DefaultTraceIdKey,
+		genRequestId:  newRequestId,
+		level:         level,
+		skipMethods:   make(map[string]struct{}),
+		redact:        make(map[protoreflect.Name]struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithMessage the message of the server and client entries, default "grpc server" and "grpc client".
+func WithMessage(server, client string) Option {
+	return func(c *config) {
+		c.serverMessage = server
+		c.clientMessage = client
+	}
+}
+
+// WithRequestIdKey the metadata key which propagates the request id, default x-request-id.
+func WithRequestIdKey(key string) Option {
+	return func(c *config) { c.requestIdKey = strings.ToLower(key) }
+}
+
+// WithTraceIdKey the metadata key which propagates the trace id, default x-trace-id.
+func WithTraceIdKey(key string) Option {
+	return func(c *config) { c.traceIdKey = strings.ToLower(key) }
+}
+
+// WithRequestIdGenerator the generator of request id when the call has none, default 16 random bytes in hex.
+func WithRequestIdGenerator(f func() string) Option {
+	return func(c *config) { c.genRequestId = f }
+}
+
+// WithLevel the level of the entry by status code,
+// default DefaultCodeToLevel for server, DefaultClientCodeToLevel for client.
+func WithLevel(f func(code codes.Code) log.Level) Option {
+	return func(c *config) { c.level = f }
+}
+
+// WithSkipMethods the full methods which are not logged, such as /grpc.health.v1.Health/Check.
+func WithSkipMethods(methods ...string) Option {
+	return func(c *config) {
+		for _, m := range methods {
+			c.skipMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithPayload log the request and response messages up to limit bytes, 0 disable.
+// the streaming messages are logged in debug level one by one.
+func WithPayload(limit int) Option {
+	return func(c *config) { c.payload = limit }
+}
+
+// WithRedact the fields of the proto message which are redacted in the payload at any depth,
+// such as password, token, the string fields are replaced with Redacted, others are cleared.
+func WithRedact(fields ...string) Option {
+	return func(c *config) {
+		for _, f := range fields {
+			c.redact[protoreflect.Name(f)] = struct{}{}
+		}
+	}
+}
+
+// WithRedactor the custom redactor of the payload, which returns the message logged.
+// it runs before the fields of WithRedact redacted.
+func WithRedactor(f func(fullMethod string, msg any) any) Option {
+	return func(c *config) { c.redactor = f }
+}
+
+// DefaultCodeToLevel the level of the server entry,
+// the client faults are info, the server faults are error, others are warn.
+func DefaultCodeToLevel(code codes.Code) log.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound,
+		codes.AlreadyExists, codes.Unauthenticated:
+		return log.InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return log.WarnLevel
+	default: // Unknown, Unimplemented, Internal, Unavailable, DataLoss
+		return log.ErrorLevel
+	}
+}
+
+// DefaultClientCodeToLevel the level of the client entry, OK is debug,
+// the expected failures are info, the unavailable and server faults are warn.
+func DefaultClientCodeToLevel(code codes.Code) log.Level {
+	switch code {
+	case codes.OK:
+		return log.DebugLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return log.InfoLevel
+	default:
+		return log.WarnLevel
+	}
+}
+
+func newRequestId() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validId the id from the peer should be printable and not too long.
+func validId(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// incomingIds returns the request id and trace id from the incoming metadata,
+// the request id is generated if absent.
+func (c *config) incomingIds(ctx context.Context) (requestId, traceId string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(c.requestIdKey); len(v) > 0 && validId(v[0]) {
+		requestId = v[0]
+	} else {
+		requestId = c.genRequestId()
+	}
+	if v := md.Get(c.traceIdKey); len(v) > 0 && validId(v[0]) {
+		traceId = v[0]
+	} else if v := md.Get(TraceParentKey); len(v) > 0 {
+		traceId = parseTraceParent(v[0])
+	}
+	return requestId, traceId
+}
+
+// outgoingContext appends the ids of the context to the outgoing metadata.
+func (c *config) outgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if id := RequestIdFromContext(ctx); id != "" && len(md.Get(c.requestIdKey)) == 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, c.requestIdKey, id)
+	}
+	if id := TraceIdFromContext(ctx); id != "" && len(md.Get(c.traceIdKey)) == 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, c.traceIdKey, id)
+	}
+	return ctx
+}
+
+// parseTraceParent returns the trace id of version-traceid-parentid-flags.
+func parseTraceParent(s string) string {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
+// payloadField returns the field of the message, the proto message is marshaled as json.
+func (c *config) payloadField(key, fullMethod string, msg any) log.Field {
+	if c.redactor != nil {
+		msg = c.redactor(fullMethod, msg)
+	}
+	var b []byte
+	if m, ok := msg.(proto.Message); ok {
+		if len(c.redact) > 0 {
+			m = proto.Clone(m)
+			redactMessage(m.ProtoReflect(), c.redact)
+		}
+		var err error
+		if b, err = protojson.Marshal(m); err != nil {
+			return log.Any(key, msg)
+		}
+	} else {
+		return log.Any(key, msg)
+	}
+	if len(b) > c.payload {
+		b = b[:c.payload]
+	}
+	return log.ByteString(key, b)
+}
+
+// redactMessage redacts the fields recursively.
+func redactMessage(m protoreflect.Message, names map[protoreflect.Name]struct{}) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if _, ok := names[fd.Name()]; ok {
+			if fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(Redacted))
+			} else {
+				m.Clear(fd)
+			}
+			return true
+		}
+		switch {
+		case fd.IsList() && fd.Kind() == protoreflect.MessageKind:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redactMessage(list.Get(i).Message(), names)
+			}
+		case fd.IsMap() && fd.MapValue().Kind() == protoreflect.MessageKind:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redactMessage(mv.Message(), names)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Kind() == protoreflect.MessageKind:
+			redactMessage(v.Message(), names)
+		}
+		return true
+	})
+}
@@ -0,0 +1,270 @@
+package grpclog_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpclogv2 "google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/grpclog"
+)
+
+// syncBuffer the server and client log in different goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *syncBuffer) {
+	buf := &syncBuffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+type healthServer struct {
+	*health.Server
+	check func(ctx context.Context)
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if s.check != nil {
+		s.check(ctx)
+	}
+	if req.Service == "broken" {
+		return nil, status.Error(codes.Internal, "broken")
+	}
+	return s.Server.Check(ctx, req)
+}
+
+func newClient(t *testing.T, srv *healthServer, server, client []grpclog.Option) (healthpb.HealthClient, *syncBuffer, *syncBuffer) {
+	t.Helper()
+	serverLog, serverBuf := newBufferLogger()
+	clientLog, clientBuf := newBufferLogger()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpclog.UnaryServerInterceptor(serverLog, server...)),
+		grpc.ChainStreamInterceptor(grpclog.StreamServerInterceptor(serverLog, server...)),
+	)
+	healthpb.RegisterHealthServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(grpclog.UnaryClientInterceptor(clientLog, client...)),
+		grpc.WithChainStreamInterceptor(grpclog.StreamClientInterceptor(clientLog, client...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+	return healthpb.NewHealthClient(cc), serverBuf, clientBuf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Unary(t *testing.T) {
+	var requestId, traceId string
+	var scoped *log.Log
+	srv := &healthServer{
+		Server: health.NewServer(),
+		check: func(ctx context.Context) {
+			requestId = grpclog.RequestIdFromContext(ctx)
+			traceId = grpclog.TraceIdFromContext(ctx)
+			scoped = grpclog.FromContext(ctx)
+		},
+	}
+	client, serverBuf, clientBuf := newClient(t, srv, nil, nil)
+
+	ctx := grpclog.ContextWithRequestId(context.Background(), "req-1")
+	ctx = metadata.AppendToOutgoingContext(ctx, grpclog.TraceParentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if requestId != "req-1" {
+		t.Errorf("want request id propagated, got: %s", requestId)
+	}
+	if traceId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("want trace id parsed from traceparent, got: %s", traceId)
+	}
+	if scoped == nil {
+		t.Fatal("want scoped logger in context")
+	}
+	assertContains(t, serverBuf.String(),
+		"level=info", `msg="grpc server"`, "requestId=req-1",
+		"method=/grpc.health.v1.Health/Check", "kind=unary", "code=OK", "latency=", "peer=",
+	)
+	assertContains(t, clientBuf.String(),
+		"level=debug", `msg="grpc client"`, "code=OK", "target=passthrough:///bufnet",
+	)
+
+	serverBuf.Reset()
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "broken"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("want internal, got: %v", err)
+	}
+	assertContains(t, serverBuf.String(), "level=error", "code=Internal", "error=")
+	if requestId == "" || requestId == "req-1" {
+		t.Errorf("want request id generated, got: %s", requestId)
+	}
+}
+
+func Test_Unary_PayloadRedact(t *testing.T) {
+	srv := &healthServer{Server: health.NewServer()}
+	srv.SetServingStatus("secret", healthpb.HealthCheckResponse_SERVING)
+	client, serverBuf, _ := newClient(t, srv, []grpclog.Option{
+		grpclog.WithPayload(1024),
+		grpclog.WithRedact("service"),
+	}, nil)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	got := serverBuf.String()
+	assertContains(t, got, "[REDACTED]", "SERVING")
+	if strings.Contains(got, "secret") {
+		t.Errorf("want service redacted, got: %s", got)
+	}
+}
+
+func Test_SkipMethods(t *testing.T) {
+	srv := &healthServer{Server: health.NewServer()}
+	client, serverBuf, _ := newClient(t, srv, []grpclog.Option{
+		grpclog.WithSkipMethods(healthpb.Health_Check_FullMethodName),
+	}, nil)
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := serverBuf.String(); got != "" {
+		t.Errorf("want skipped, got: %s", got)
+	}
+}
+
+func Test_Stream(t *testing.T) {
+	srv := &healthServer{Server: health.NewServer()}
+	client, serverBuf, clientBuf := newClient(t, srv, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err = stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("want canceled, got: %v", err)
+	}
+	assertContains(t, clientBuf.String(),
+		`msg="grpc client"`, "kind=stream", "code=Canceled", "sent=1", "received=1",
+	)
+
+	// the watch returns after the server stream is canceled.
+	for i := 0; i < 100 && !strings.Contains(serverBuf.String(), "grpc server"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertContains(t, serverBuf.String(), `msg="grpc server"`, "kind=stream", "code=Canceled", "received=1")
+}
+
+func Test_Stream_CancelWithoutRecv(t *testing.T) {
+	srv := &healthServer{Server: health.NewServer()}
+	client, _, clientBuf := newClient(t, srv, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for i := 0; i < 100 && !strings.Contains(clientBuf.String(), "grpc client"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertContains(t, clientBuf.String(), `msg="grpc client"`, "kind=stream", "code=Canceled", "received=1")
+	if n := strings.Count(clientBuf.String(), "grpc client"); n != 1 {
+		t.Errorf("want one entry, got: %d", n)
+	}
+}
+
+func Test_LoggerV2(t *testing.T) {
+	l, buf := newBufferLogger()
+	g := grpclog.NewLoggerV2(l, 2)
+	g.Infoln("hello", "world")
+	g.Warningf("warn %d", 1)
+	g.Error("failed")
+	assertContains(t, buf.String(),
+		`level=info msg="hello world"`, `level=warn msg="warn 1"`, "level=error msg=failed",
+	)
+	if !g.V(2) || g.V(3) {
+		t.Errorf("want verbosity 2")
+	}
+}
+
+func Test_LoggerV2_Caller(t *testing.T) {
+	// the caller skip 1 reports the caller of *log.Log.
+	l, buf := newBufferLogger(log.WithAddCaller(true), log.WithCallerSkip(1))
+	g := grpclog.NewLoggerV2(l, 0)
+
+	_, _, line, _ := runtime.Caller(0)
+	g.Info("direct")
+	assertContains(t, buf.String(), "grpclog_test.go:"+strconv.Itoa(line+1)+" ")
+
+	// the component logger of grpc-go goes through InfoDepth.
+	grpclogv2.SetLoggerV2(g)
+	defer grpclogv2.SetLoggerV2(grpclogv2.NewLoggerV2(io.Discard, io.Discard, io.Discard))
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	grpclogv2.Component("test").Warningf("depth %d", 1)
+	assertContains(t, buf.String(), "grpclog_test.go:"+strconv.Itoa(line+1)+" ", `msg="[test] depth 1"`)
+}
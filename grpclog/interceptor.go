@@ -0,0 +1,279 @@
+package grpclog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/things-go/log"
+)
+
+// call kind defined
+const (
+	KindUnary  = "unary"
+	KindStream = "stream"
+)
+
+// UnaryServerInterceptor returns the interceptor which injects the request id and trace id
+// into the context, stores the request-scoped logger, and writes one entry per call.
+func UnaryServerInterceptor(l *log.Log, opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(DefaultCodeToLevel, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, scoped := c.serverContext(ctx, l)
+		if _, ok := c.skipMethods[info.FullMethod]; ok {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		fields := c.fields(info.FullMethod, KindUnary, peerAddr(ctx), err, time.Since(start))
+		if c.payload > 0 {
+			fields = append(fields, c.payloadField("request", info.FullMethod, req))
+			if err == nil {
+				fields = append(fields, c.payloadField("response", info.FullMethod, resp))
+			}
+		}
+		scoped.Logx(ctx, c.level(status.Code(err)), c.serverMessage, fields...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns the interceptor which injects the request id and trace id
+// into the context of the stream, stores the request-scoped logger, and writes one entry per call.
+func StreamServerInterceptor(l *log.Log, opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(DefaultCodeToLevel, opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, scoped := c.serverContext(ss.Context(), l)
+		if _, ok := c.skipMethods[info.FullMethod]; ok {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		}
+
+		start := time.Now()
+		stream := &serverStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			messages:     messages{config: c, log: scoped, method: info.FullMethod},
+		}
+		err := handler(srv, stream)
+		fields := c.fields(info.FullMethod, KindStream, peerAddr(ctx), err, time.Since(start))
+		fields = append(fields, stream.counts()...)
+		scoped.Logx(ctx, c.level(status.Code(err)), c.serverMessage, fields...)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns the interceptor which propagates the request id and trace id
+// of the context to the outgoing metadata, and writes one entry per call.
+func UnaryClientInterceptor(l *log.Log, opts ...Option) grpc.UnaryClientInterceptor {
+	c := newConfig(DefaultClientCodeToLevel, opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		ctx = c.outgoingContext(ctx)
+		if _, ok := c.skipMethods[method]; ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		var p peer.Peer
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, append(callOpts, grpc.Peer(&p))...)
+		addr := ""
+		if p.Addr != nil {
+			addr = p.Addr.String()
+		}
+		fields := c.fields(method, KindUnary, addr, err, time.Since(start))
+		fields = append(fields, log.String("target", cc.Target()))
+		if c.payload > 0 {
+			fields = append(fields, c.payloadField("request", method, req))
+			if err == nil {
+				fields = append(fields, c.payloadField("response", method, reply))
+			}
+		}
+		l.Logx(ctx, c.level(status.Code(err)), c.clientMessage, fields...)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns the interceptor which propagates the request id and trace id
+// of the context to the outgoing metadata, and writes one entry when the stream ends.
+func StreamClientInterceptor(l *log.Log, opts ...Option) grpc.StreamClientInterceptor {
+	c := newConfig(DefaultClientCodeToLevel, opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = c.outgoingContext(ctx)
+		if _, ok := c.skipMethods[method]; ok {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		finish := func(err error, counts []log.Field) {
+			fields := c.fields(method, KindStream, "", err, time.Since(start))
+			fields = append(fields, log.String("target", cc.Target()))
+			fields = append(fields, counts...)
+			l.Logx(ctx, c.level(status.Code(err)), c.clientMessage, fields...)
+		}
+		if err != nil {
+			finish(err, nil)
+			return nil, err
+		}
+		stream := &clientStream{
+			ClientStream: cs,
+			messages:     messages{config: c, log: l, method: method},
+			ctx:          ctx,
+			finish:       finish,
+			finished:     make(chan struct{}),
+		}
+		// the caller may stop reading once the context is canceled, so the entry is written on cancellation too.
+		go func() {
+			select {
+			case <-ctx.Done():
+				stream.done(status.FromContextError(ctx.Err()).Err())
+			case <-stream.finished:
+			}
+		}()
+		return stream, nil
+	}
+}
+
+// serverContext returns the context which carries the ids and the request-scoped logger.
+func (c *config) serverContext(ctx context.Context, l *log.Log) (context.Context, *log.Log) {
+	requestId, traceId := c.incomingIds(ctx)
+	scoped := l.With(log.String("requestId", requestId))
+	ctx = ContextWithRequestId(ctx, requestId)
+	if traceId != "" {
+		ctx = ContextWithTraceId(ctx, traceId)
+	}
+	return context.WithValue(ctx, loggerKey{}, scoped), scoped
+}
+
+func (c *config) fields(method, kind, peer string, err error, latency time.Duration) []log.Field {
+	fields := make([]log.Field, 0, 10)
+	fields = append(fields,
+		log.String("method", method),
+		log.String("kind", kind),
+		log.String("code", status.Code(err).String()),
+		log.Duration("latency", latency),
+	)
+	if peer != "" {
+		fields = append(fields, log.String("peer", peer))
+	}
+	if err != nil {
+		fields = append(fields, log.Err(err))
+	}
+	return fields
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// messages counts the messages of the stream, and logs each message if the payload enabled.
+type messages struct {
+	*config
+	log      *log.Log
+	method   string
+	sent     int64
+	received int64
+}
+
+func (m *messages) onSend(ctx context.Context, msg any) {
+	m.sent++
+	if m.config != nil && m.payload > 0 {
+		m.log.Logx(ctx, log.DebugLevel, "grpc stream send",
+			log.String("method", m.method), m.payloadField("payload", m.method, msg))
+	}
+}
+
+func (m *messages) onRecv(ctx context.Context, msg any) {
+	m.received++
+	if m.config != nil && m.payload > 0 {
+		m.log.Logx(ctx, log.DebugLevel, "grpc stream recv",
+			log.String("method", m.method), m.payloadField("payload", m.method, msg))
+	}
+}
+
+func (m *messages) counts() []log.Field {
+	return []log.Field{log.Int64("sent", m.sent), log.Int64("received", m.received)}
+}
+
+// serverStream overrides the context of the stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	messages
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func (s *serverStream) SendMsg(msg any) error {
+	err := s.ServerStream.SendMsg(msg)
+	if err == nil {
+		s.onSend(s.ctx, msg)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(msg any) error {
+	err := s.ServerStream.RecvMsg(msg)
+	if err == nil {
+		s.onRecv(s.ctx, msg)
+	}
+	return err
+}
+
+// clientStream writes the entry when the stream ends or the context is canceled,
+// io.EOF of RecvMsg is the OK status.
+type clientStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	finish   func(err error, counts []log.Field)
+	finished chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	messages
+}
+
+func (s *clientStream) SendMsg(msg any) error {
+	err := s.ClientStream.SendMsg(msg)
+	if err == nil {
+		s.mu.Lock()
+		s.onSend(s.ctx, msg)
+		s.mu.Unlock()
+	} else if !errors.Is(err, io.EOF) {
+		// io.EOF means the stream is aborted, the status is returned by RecvMsg.
+		s.done(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(msg any) error {
+	err := s.ClientStream.RecvMsg(msg)
+	switch {
+	case err == nil:
+		s.mu.Lock()
+		s.onRecv(s.ctx, msg)
+		s.mu.Unlock()
+	case errors.Is(err, io.EOF):
+		s.done(nil)
+	default:
+		s.done(err)
+	}
+	return err
+}
+
+func (s *clientStream) done(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		counts := s.counts()
+		s.mu.Unlock()
+		s.finish(err, counts)
+		close(s.finished)
+	})
+}
@@ -0,0 +1,137 @@
+package grpclog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	grpclogv2 "google.golang.org/grpc/grpclog"
+
+	"github.com/things-go/log"
+)
+
+var _ grpclogv2.DepthLoggerV2 = (*LoggerV2)(nil)
+
+// LoggerV2 implements grpclog.LoggerV2 and grpclog.DepthLoggerV2 of grpc-go,
+// which routes the internal logs through *log.Log.
+type LoggerV2 struct {
+	log       *log.Log
+	verbosity int
+}
+
+// NewLoggerV2 returns the grpclog.LoggerV2, verbosity is the verbose level of grpc-go,
+// same as GRPC_GO_LOG_VERBOSITY_LEVEL.
+func NewLoggerV2(l *log.Log, verbosity int) *LoggerV2 {
+	// skip the frame of LoggerV2 itself, so the caller is who calls it.
+	// all the methods log with Logx, which the CallerSkip is relative to.
+	return &LoggerV2{log: l.WithOptions(zap.AddCallerSkip(1)), verbosity: verbosity}
+}
+
+// ReplaceGrpcLoggerV2 replaces the logger of grpc-go, it is not mutex-protected,
+// and should be called before any gRPC functions.
+//
+//	grpclog.ReplaceGrpcLoggerV2(l.Named("grpc"), 0)
+func ReplaceGrpcLoggerV2(l *log.Log, verbosity int) {
+	grpclogv2.SetLoggerV2(NewLoggerV2(l, verbosity))
+}
+
+func sprintln(args []any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// Info logs to INFO log. Arguments are handled in the manner of fmt.Print.
+func (g *LoggerV2) Info(args ...any) {
+	g.log.Logx(context.Background(), log.InfoLevel, fmt.Sprint(args...))
+}
+
+// Infoln logs to INFO log. Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) Infoln(args ...any) {
+	g.log.Logx(context.Background(), log.InfoLevel, sprintln(args))
+}
+
+// Infof logs to INFO log. Arguments are handled in the manner of fmt.Printf.
+func (g *LoggerV2) Infof(format string, args ...any) {
+	g.log.Logx(context.Background(), log.InfoLevel, fmt.Sprintf(format, args...))
+}
+
+// Warning logs to WARNING log. Arguments are handled in the manner of fmt.Print.
+func (g *LoggerV2) Warning(args ...any) {
+	g.log.Logx(context.Background(), log.WarnLevel, fmt.Sprint(args...))
+}
+
+// Warningln logs to WARNING log. Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) Warningln(args ...any) {
+	g.log.Logx(context.Background(), log.WarnLevel, sprintln(args))
+}
+
+// Warningf logs to WARNING log. Arguments are handled in the manner of fmt.Printf.
+func (g *LoggerV2) Warningf(format string, args ...any) {
+	g.log.Logx(context.Background(), log.WarnLevel, fmt.Sprintf(format, args...))
+}
+
+// Error logs to ERROR log. Arguments are handled in the manner of fmt.Print.
+func (g *LoggerV2) Error(args ...any) {
+	g.log.Logx(context.Background(), log.ErrorLevel, fmt.Sprint(args...))
+}
+
+// Errorln logs to ERROR log. Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) Errorln(args ...any) {
+	g.log.Logx(context.Background(), log.ErrorLevel, sprintln(args))
+}
+
+// Errorf logs to ERROR log. Arguments are handled in the manner of fmt.Printf.
+func (g *LoggerV2) Errorf(format string, args ...any) {
+	g.log.Logx(context.Background(), log.ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs to ERROR log, then calls os.Exit(1). Arguments are handled in the manner of fmt.Print.
+func (g *LoggerV2) Fatal(args ...any) {
+	g.log.Logx(context.Background(), log.FatalLevel, fmt.Sprint(args...))
+}
+
+// Fatalln logs to ERROR log, then calls os.Exit(1). Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) Fatalln(args ...any) {
+	g.log.Logx(context.Background(), log.FatalLevel, sprintln(args))
+}
+
+// Fatalf logs to ERROR log, then calls os.Exit(1). Arguments are handled in the manner of fmt.Printf.
+func (g *LoggerV2) Fatalf(format string, args ...any) {
+	g.log.Logx(context.Background(), log.FatalLevel, fmt.Sprintf(format, args...))
+}
+
+// depth returns the logger which skips depth more frames. same as glog,
+// depth 0 means the caller of the function which calls XxxDepth.
+func (g *LoggerV2) depth(depth int) *log.Log {
+	return g.log.WithOptions(zap.AddCallerSkip(depth + 1))
+}
+
+// InfoDepth logs to INFO log at the specified depth. Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) InfoDepth(depth int, args ...any) {
+	if g.log.Enabled(log.InfoLevel) {
+		g.depth(depth).Logx(context.Background(), log.InfoLevel, sprintln(args))
+	}
+}
+
+// WarningDepth logs to WARNING log at the specified depth. Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) WarningDepth(depth int, args ...any) {
+	if g.log.Enabled(log.WarnLevel) {
+		g.depth(depth).Logx(context.Background(), log.WarnLevel, sprintln(args))
+	}
+}
+
+// ErrorDepth logs to ERROR log at the specified depth. Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) ErrorDepth(depth int, args ...any) {
+	if g.log.Enabled(log.ErrorLevel) {
+		g.depth(depth).Logx(context.Background(), log.ErrorLevel, sprintln(args))
+	}
+}
+
+// FatalDepth logs to FATAL log at the specified depth, then calls os.Exit(1).
+// Arguments are handled in the manner of fmt.Println.
+func (g *LoggerV2) FatalDepth(depth int, args ...any) {
+	g.depth(depth).Logx(context.Background(), log.FatalLevel, sprintln(args))
+}
+
+// V reports whether verbosity level l is at least the requested verbose level.
+func (g *LoggerV2) V(l int) bool { return l <= g.verbosity }
@@ -0,0 +1,19 @@
+module github.com/things-go/log/hcloglog
+
+go 1.21
+
+require (
+	github.com/hashicorp/go-hclog v1.6.3
+	github.com/things-go/log v0.0.0-20261019071040-f877d589fc7f
+)
+
+require (
+	github.com/fatih/color v1.13.0 // indirect
+	github.com/klauspost/compress v1.17.10 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/natefinch/lumberjack v2.0.0+incompatible // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
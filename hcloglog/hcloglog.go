@@ -0,0 +1,204 @@
+// Package hcloglog provides the hclog.Logger backed by *log.Log, such as for the HashiCorp libraries.
+package hcloglog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/things-go/log"
+)
+
+var _ hclog.Logger = (*Logger)(nil)
+
+// Logger implements hclog.Logger, the trace level of hclog is mapped onto debug.
+// SetLevel changes the level of *log.Log, which is shared by the parent and the children.
+//
+//	raftConfig.Logger = hcloglog.New(l.Named("raft"))
+type Logger struct {
+	root *log.Log // the logger without name and implied args, used by ResetNamed
+	log  *log.Log
+	name string
+	args []any
+}
+
+// New returns the hclog logger.
+func New(l *log.Log) *Logger {
+	return &Logger{root: l, log: l}
+}
+
+// Log implements hclog.Logger.
+func (l *Logger) Log(level hclog.Level, msg string, args ...any) {
+	if level == hclog.Off {
+		return
+	}
+	l.log.Logw(context.Background(), toLevel(level), msg, args...)
+}
+
+// Trace implements hclog.Logger.
+func (l *Logger) Trace(msg string, args ...any) { l.Log(hclog.Trace, msg, args...) }
+
+// Debug implements hclog.Logger.
+func (l *Logger) Debug(msg string, args ...any) { l.Log(hclog.Debug, msg, args...) }
+
+// Info implements hclog.Logger.
+func (l *Logger) Info(msg string, args ...any) { l.Log(hclog.Info, msg, args...) }
+
+// Warn implements hclog.Logger.
+func (l *Logger) Warn(msg string, args ...any) { l.Log(hclog.Warn, msg, args...) }
+
+// Error implements hclog.Logger.
+func (l *Logger) Error(msg string, args ...any) { l.Log(hclog.Error, msg, args...) }
+
+// IsTrace implements hclog.Logger.
+func (l *Logger) IsTrace() bool { return l.log.Enabled(log.DebugLevel) }
+
+// IsDebug implements hclog.Logger.
+func (l *Logger) IsDebug() bool { return l.log.Enabled(log.DebugLevel) }
+
+// IsInfo implements hclog.Logger.
+func (l *Logger) IsInfo() bool { return l.log.Enabled(log.InfoLevel) }
+
+// IsWarn implements hclog.Logger.
+func (l *Logger) IsWarn() bool { return l.log.Enabled(log.WarnLevel) }
+
+// IsError implements hclog.Logger.
+func (l *Logger) IsError() bool { return l.log.Enabled(log.ErrorLevel) }
+
+// ImpliedArgs implements hclog.Logger.
+func (l *Logger) ImpliedArgs() []any { return append([]any(nil), l.args...) }
+
+// With implements hclog.Logger.
+func (l *Logger) With(args ...any) hclog.Logger {
+	return &Logger{
+		root: l.root,
+		log:  l.log.Withw(args...),
+		name: l.name,
+		args: append(l.ImpliedArgs(), args...),
+	}
+}
+
+// Name implements hclog.Logger.
+func (l *Logger) Name() string { return l.name }
+
+// Named implements hclog.Logger.
+func (l *Logger) Named(name string) hclog.Logger {
+	fullName := name
+	if l.name != "" {
+		fullName = l.name + "." + name
+	}
+	return &Logger{
+		root: l.root,
+		log:  l.log.Named(name),
+		name: fullName,
+		args: l.args,
+	}
+}
+
+// ResetNamed implements hclog.Logger.
+func (l *Logger) ResetNamed(name string) hclog.Logger {
+	return &Logger{
+		root: l.root,
+		log:  l.root.Named(name).Withw(l.args...),
+		name: name,
+		args: l.args,
+	}
+}
+
+// SetLevel implements hclog.Logger.
+func (l *Logger) SetLevel(level hclog.Level) {
+	if level == hclog.Off {
+		l.log.SetLevel(log.FatalLevel + 1)
+		return
+	}
+	l.log.SetLevel(toLevel(level))
+}
+
+// GetLevel implements hclog.Logger.
+func (l *Logger) GetLevel() hclog.Level {
+	switch lv := l.log.GetLevel(); {
+	case lv <= log.DebugLevel:
+		return hclog.Debug
+	case lv == log.InfoLevel:
+		return hclog.Info
+	case lv == log.WarnLevel:
+		return hclog.Warn
+	case lv <= log.FatalLevel:
+		return hclog.Error
+	default:
+		return hclog.Off
+	}
+}
+
+// StandardLogger implements hclog.Logger.
+func (l *Logger) StandardLogger(opts *hclog.StandardLoggerOptions) *stdlog.Logger {
+	return stdlog.New(l.StandardWriter(opts), "", 0)
+}
+
+// StandardWriter implements hclog.Logger.
+func (l *Logger) StandardWriter(opts *hclog.StandardLoggerOptions) io.Writer {
+	if opts == nil {
+		opts = &hclog.StandardLoggerOptions{}
+	}
+	return &stdWriter{log: l, opts: *opts}
+}
+
+func toLevel(level hclog.Level) log.Level {
+	switch level {
+	case hclog.Trace, hclog.Debug:
+		return log.DebugLevel
+	case hclog.Warn:
+		return log.WarnLevel
+	case hclog.Error:
+		return log.ErrorLevel
+	default: // NoLevel, Info
+		return log.InfoLevel
+	}
+}
+
+// stdWriter writes the line of the standard logger, the level is inferred from the prefix,
+// such as [DEBUG], [WARN], if InferLevels.
+type stdWriter struct {
+	log  *Logger
+	opts hclog.StandardLoggerOptions
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimRight(p, " \t\n"))
+	level := hclog.Info
+	if w.opts.ForceLevel != hclog.NoLevel {
+		level = w.opts.ForceLevel
+	} else if w.opts.InferLevels {
+		if w.opts.InferLevelsWithTimestamp {
+			if i := strings.IndexByte(msg, '['); i > 0 {
+				msg = msg[i:]
+			}
+		}
+		level, msg = inferLevel(msg)
+	}
+	w.log.Log(level, msg)
+	return len(p), nil
+}
+
+func inferLevel(msg string) (hclog.Level, string) {
+	for _, p := range []struct {
+		prefix string
+		level  hclog.Level
+	}{
+		{"[TRACE]", hclog.Trace},
+		{"[DEBUG]", hclog.Debug},
+		{"[INFO]", hclog.Info},
+		{"[WARN]", hclog.Warn},
+		{"[ERROR]", hclog.Error},
+		{"[ERR]", hclog.Error},
+	} {
+		if strings.HasPrefix(msg, p.prefix) {
+			return p.level, strings.TrimSpace(msg[len(p.prefix):])
+		}
+	}
+	return hclog.Info, msg
+}
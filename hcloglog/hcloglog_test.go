@@ -0,0 +1,67 @@
+package hcloglog_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/hcloglog"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Logger(t *testing.T) {
+	l, buf := newBufferLogger()
+	logger := hcloglog.New(l).Named("raft").With("node", "n1")
+
+	logger.Info("elected", "term", 2)
+	assertContains(t, buf.String(), "level=info", "logger=raft", "msg=elected", "node=n1", "term=2")
+	if logger.Name() != "raft" {
+		t.Errorf("want name raft, got: %s", logger.Name())
+	}
+
+	buf.Reset()
+	logger.Trace("heartbeat")
+	assertContains(t, buf.String(), "level=debug", "msg=heartbeat")
+
+	buf.Reset()
+	logger.Named("fsm").ResetNamed("snapshot").Error("failed", "error", errors.New("disk full"))
+	assertContains(t, buf.String(), "level=error", "logger=snapshot", "node=n1", `error="disk full"`)
+
+	if args := logger.ImpliedArgs(); len(args) != 2 {
+		t.Errorf("want implied args, got: %v", args)
+	}
+
+	logger.SetLevel(hclog.Warn)
+	if logger.IsInfo() || !logger.IsWarn() || logger.GetLevel() != hclog.Warn {
+		t.Errorf("want level warn, got: %s", logger.GetLevel())
+	}
+}
+
+func Test_StandardLogger(t *testing.T) {
+	l, buf := newBufferLogger()
+	std := hcloglog.New(l).StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true})
+	std.Println("[WARN] deprecated option")
+	assertContains(t, buf.String(), "level=warn", `msg="deprecated option"`)
+}
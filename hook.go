@@ -0,0 +1,269 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Hook is invoked with the entry and the fields before the entry is written,
+// the fields include the fields of With, the Valuer and the call site.
+// Hook is called on the logging hot path, which should be fast and concurrency safe.
+type Hook func(ent zapcore.Entry, fields []Field)
+
+// WithHooks returns the child logger with the hooks, which are invoked for the entries
+// at or above the level, after the Valuer evaluated, so the context fields are visible.
+// the fields added by With before WithHooks are not visible to the hooks.
+func (l *Log) WithHooks(hooks ...Hook) *Log {
+	if len(hooks) == 0 {
+		return l
+	}
+	return &Log{
+		log: l.log.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &hookCore{core: core, level: l.level, hooks: hooks}
+		})),
+		level: l.level,
+		fn:    l.fn,
+		ctx:   l.ctx,
+	}
+}
+
+// MetricRule 日志转指标规则, 日志匹配所有已设置的条件时, 调用 Observe
+type MetricRule struct {
+	// Level 日志等级, 匹配大于等于该等级的日志, 如 log.ErrorLevel, 默认空, 匹配所有等级
+	Level zapcore.LevelEnabler
+	// Logger 日志名称, 默认空, 匹配所有
+	Logger string
+	// Message 日志消息, 完全匹配, 默认空, 匹配所有
+	Message string
+	// MessageRegexp 日志消息正则, 默认空, 匹配所有
+	MessageRegexp *regexp.Regexp
+	// Fields 字段值, 字段格式化为字符串后比较, 如 {"provider": "stripe"}
+	Fields map[string]string
+	// Match 自定义条件, 默认空, 匹配所有
+	Match func(ent zapcore.Entry, fields []Field) bool
+	// Labels 标签字段key, 字段值按顺序作为 Observe 的标签值, 字段不存在时为空字符串, 如 provider
+	Labels []string
+	// Value 数值字段key, 如 duration, 时间间隔以秒为单位, 默认空, 值为1用于计数
+	// 日志不含该数值字段时忽略
+	Value string
+	// Observe 指标回调, 如计数器增加 value 或直方图观察 value, 见 promlog.Counter, promlog.Observer
+	Observe func(value float64, labels []string)
+}
+
+// MetricHook returns the hook which extracts the metrics from the entries by the rules,
+// such as counting the payment failures by provider:
+//
+//	l = l.WithHooks(log.MetricHook(log.MetricRule{
+//		Level:   log.ErrorLevel,
+//		Message: "payment_failed",
+//		Labels:  []string{"provider"},
+//		Observe: promlog.Counter(paymentFailed),
+//	}))
+func MetricHook(rules ...MetricRule) Hook {
+	return func(ent zapcore.Entry, fields []Field) {
+		for i := range rules {
+			rules[i].observe(ent, fields)
+		}
+	}
+}
+
+func (r *MetricRule) observe(ent zapcore.Entry, fields []Field) {
+	if r.Observe == nil || !r.match(ent, fields) {
+		return
+	}
+	value := 1.0
+	if r.Value != "" {
+		v, ok := lookupField(fields, r.Value)
+		if !ok {
+			return
+		}
+		if value, ok = toFloat(v); !ok {
+			return
+		}
+	}
+	var labels []string
+	if len(r.Labels) > 0 {
+		labels = make([]string, len(r.Labels))
+		for i, key := range r.Labels {
+			if v, ok := lookupField(fields, key); ok {
+				labels[i] = fmt.Sprint(v)
+			}
+		}
+	}
+	r.Observe(value, labels)
+}
+
+func (r *MetricRule) match(ent zapcore.Entry, fields []Field) bool {
+	if r.Level != nil && !r.Level.Enabled(ent.Level) {
+		return false
+	}
+	if r.Logger != "" && r.Logger != ent.LoggerName {
+		return false
+	}
+	if r.Message != "" && r.Message != ent.Message {
+		return false
+	}
+	if r.MessageRegexp != nil && !r.MessageRegexp.MatchString(ent.Message) {
+		return false
+	}
+	for key, want := range r.Fields {
+		v, ok := lookupField(fields, key)
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return r.Match == nil || r.Match(ent, fields)
+}
+
+// lookupField returns the value of the last field of the key, the same as encoded by the MapObjectEncoder,
+// the common types are read from the field directly, without the encoder.
+func lookupField(fields []Field, key string) (any, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := &fields[i]
+		if f.Key != key {
+			continue
+		}
+		switch f.Type {
+		case zapcore.StringType:
+			return f.String, true
+		case zapcore.BoolType:
+			return f.Integer == 1, true
+		case zapcore.Int64Type:
+			return f.Integer, true
+		case zapcore.Int32Type:
+			return int32(f.Integer), true
+		case zapcore.Int16Type:
+			return int16(f.Integer), true
+		case zapcore.Int8Type:
+			return int8(f.Integer), true
+		case zapcore.Uint64Type:
+			return uint64(f.Integer), true
+		case zapcore.Uint32Type:
+			return uint32(f.Integer), true
+		case zapcore.Uint16Type:
+			return uint16(f.Integer), true
+		case zapcore.Uint8Type:
+			return uint8(f.Integer), true
+		case zapcore.UintptrType:
+			return uintptr(f.Integer), true
+		case zapcore.Float64Type:
+			return math.Float64frombits(uint64(f.Integer)), true
+		case zapcore.Float32Type:
+			return math.Float32frombits(uint32(f.Integer)), true
+		case zapcore.DurationType:
+			return time.Duration(f.Integer), true
+		case zapcore.ReflectType:
+			return f.Interface, true
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		v, ok := enc.Fields[key]
+		return v, ok
+	}
+	return nil, false
+}
+
+// toFloat returns the numeric value, the time.Duration in seconds.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case time.Duration:
+		return n.Seconds(), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uintptr:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// hookCore invokes the hooks with the fields of With and the entry.
+type hookCore struct {
+	core   zapcore.Core
+	level  zapcore.LevelEnabler
+	hooks  []Hook
+	fields []Field
+}
+
+func (c *hookCore) Enabled(lvl zapcore.Level) bool { return c.core.Enabled(lvl) }
+
+func (c *hookCore) With(fields []Field) zapcore.Core {
+	return &hookCore{
+		core:   c.core.With(fields),
+		level:  c.level,
+		hooks:  c.hooks,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+// Check adds the hookRunner only for the entries at or above the level, the entries
+// enabled by the core which are below the level, such as recorded by the flight recorder, are skipped.
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.level.Enabled(ent.Level) {
+		ce = ce.AddCore(ent, hookRunner{c})
+	}
+	return c.core.Check(ent, ce)
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []Field) error {
+	hookRunner{c}.run(ent, fields)
+	return c.core.Write(ent, fields)
+}
+
+func (c *hookCore) Sync() error     { return c.core.Sync() }
+func (c *hookCore) recording() bool { return isRecording(c.core) }
+
+// Close closes the core if it is closable, see Log.Close.
+func (c *hookCore) Close() error {
+	if closer, ok := c.core.(io.Closer); ok {
+		return closer.Close()
+	}
+	return c.core.Sync()
+}
+
+// hookRunner the core only runs the hooks.
+type hookRunner struct{ c *hookCore }
+
+func (hookRunner) Enabled(zapcore.Level) bool  { return true }
+func (r hookRunner) With([]Field) zapcore.Core { return r }
+func (r hookRunner) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, r)
+}
+func (r hookRunner) Write(ent zapcore.Entry, fields []Field) error {
+	r.run(ent, fields)
+	return nil
+}
+func (hookRunner) Sync() error { return nil }
+
+func (r hookRunner) run(ent zapcore.Entry, fields []Field) {
+	if len(r.c.fields) > 0 {
+		fields = append(r.c.fields[:len(r.c.fields):len(r.c.fields)], fields...)
+	}
+	for _, h := range r.c.hooks {
+		h(ent, fields)
+	}
+}
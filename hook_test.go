@@ -0,0 +1,136 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+type observed struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (o *observed) observe(value float64, labels []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values[strings.Join(labels, ",")] += value
+}
+
+func Test_Hook_MetricRule(t *testing.T) {
+	failed := &observed{values: map[string]float64{}}
+	latency := &observed{values: map[string]float64{}}
+	timeout := &observed{values: map[string]float64{}}
+
+	type tenantKey struct{}
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, &bytes.Buffer{}),
+		log.WithLevel("info"),
+	).WithValuer(log.FromString("tenant", func(ctx context.Context) string {
+		s, _ := ctx.Value(tenantKey{}).(string)
+		return s
+	})).WithHooks(log.MetricHook(
+		log.MetricRule{
+			Level:   log.ErrorLevel,
+			Message: "payment_failed",
+			Labels:  []string{"provider", "tenant"},
+			Observe: failed.observe,
+		},
+		log.MetricRule{
+			Logger:  "http",
+			Value:   "duration",
+			Labels:  []string{"route"},
+			Observe: latency.observe,
+		},
+		log.MetricRule{
+			MessageRegexp: regexp.MustCompile(`timeout$`),
+			Fields:        map[string]string{"retry": "true"},
+			Observe:       timeout.observe,
+		},
+	))
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	l.ErrorxContext(ctx, "payment_failed", log.String("provider", "stripe"))
+	l.Errorw("payment_failed", "provider", "stripe")
+	l.With(log.String("provider", "paypal")).Errorx("payment_failed")
+	l.Warnx("payment_failed", log.String("provider", "stripe")) // below the rule level
+
+	h := l.Named("http").With(log.String("route", "/pay"))
+	h.Infox("request", log.Duration("duration", 1500*time.Millisecond))
+	h.Infox("request", log.Int("duration", 2))
+	h.Infox("request")                                         // no value
+	h.Debugx("request", log.Duration("duration", time.Second)) // below the logger level
+
+	l.Warnx("upstream timeout", log.Bool("retry", true))
+	l.Warnx("upstream timeout", log.Bool("retry", false))
+
+	if want := map[string]float64{"stripe,acme": 1, "stripe,": 1, "paypal,": 1}; !equalValues(failed.values, want) {
+		t.Errorf("want %v, got: %v", want, failed.values)
+	}
+	if want := map[string]float64{"/pay": 3.5}; !equalValues(latency.values, want) {
+		t.Errorf("want %v, got: %v", want, latency.values)
+	}
+	if want := map[string]float64{"": 1}; !equalValues(timeout.values, want) {
+		t.Errorf("want %v, got: %v", want, timeout.values)
+	}
+}
+
+func Test_Hook_MetricRule_FieldTypes(t *testing.T) {
+	got := &observed{values: map[string]float64{}}
+	hook := log.MetricHook(log.MetricRule{
+		Value:   "value",
+		Labels:  []string{"label"},
+		Observe: got.observe,
+	})
+	for _, tt := range []struct {
+		value log.Field
+		label log.Field
+	}{
+		{log.Float64("value", 0.5), log.Stringer("label", time.Second)},
+		{log.Float32("value", 0.25), log.Bool("label", true)},
+		{log.Uint8("value", 2), log.Int64("label", -1)},
+		{log.Any("value", 4), log.Reflect("label", []int{1})},
+	} {
+		hook(zapcore.Entry{}, []log.Field{tt.value, tt.label})
+	}
+	want := map[string]float64{"1s": 0.5, "true": 0.25, "-1": 2, "[1]": 4}
+	if !equalValues(got.values, want) {
+		t.Errorf("want %v, got: %v", want, got.values)
+	}
+}
+
+func Test_Hook_Custom(t *testing.T) {
+	var messages []string
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, &bytes.Buffer{}),
+		log.WithLevel("info"),
+		log.WithFlightRecorder(log.NewFlightRecorder(log.FlightRecorderConfig{})),
+	).WithHooks(func(ent zapcore.Entry, _ []log.Field) {
+		messages = append(messages, ent.Message)
+	})
+	l.Debugx("recorded") // enabled by the flight recorder, but below the level
+	l.Infox("hello")
+	if len(messages) != 1 || messages[0] != "hello" {
+		t.Errorf("want only hello, got: %v", messages)
+	}
+}
+
+func equalValues(got, want map[string]float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
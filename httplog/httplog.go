@@ -0,0 +1,348 @@
+// Package httplog provides the net/http middleware for access logging
+// and request-scoped loggers built on *log.Log.
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+// DefaultRequestIdHeader the default header of request id.
+const DefaultRequestIdHeader = "X-Request-Id"
+
+type requestIdKey struct{}
+type loggerKey struct{}
+
+// ContextWithRequestId returns a context which carries the request id.
+func ContextWithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestIdFromContext returns the request id, which is used with the RequestId preset
+// by the loggers not from FromContext, such as the global logger:
+//
+//	log.SetDefaultValuer(log.RequestId(httplog.RequestIdFromContext))
+//
+// the logger passed to Middleware should not have the preset, the request-scoped logger
+// already has the requestId field.
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// FromContext returns the request-scoped logger stored by the middleware,
+// which has the requestId field, it returns nil if not found.
+func FromContext(ctx context.Context) *log.Log {
+	l, _ := ctx.Value(loggerKey{}).(*log.Log)
+	return l
+}
+
+// Option the option of the middleware.
+type Option func(c *config)
+
+type config struct {
+	message         string
+	requestIdHeader string
+	genRequestId    func() string
+	route           func(r *http.Request) string
+	skip            func(r *http.Request) bool
+	skipPaths       map[string]struct{}
+	level           func(status int) log.Level
+	requestHeaders  []string
+	responseHeaders []string
+	requestBody     int
+	responseBody    int
+	buffered        bool
+	bufferedLatency time.Duration
+}
+
+// WithMessage the message of the access entry, default "http access".
+func WithMessage(msg string) Option {
+	return func(c *config) { c.message = msg }
+}
+
+// WithRequestIdHeader the header which propagates the request id, default X-Request-Id.
+func WithRequestIdHeader(header string) Option {
+	return func(c *config) { c.requestIdHeader = header }
+}
+
+// WithRequestIdGenerator the generator of request id when the request has none, default 16 random bytes in hex.
+func WithRequestIdGenerator(f func() string) Option {
+	return func(c *config) { c.genRequestId = f }
+}
+
+// WithRoute the route of the request, such as the pattern of the router, default the url path.
+func WithRoute(f func(r *http.Request) string) Option {
+	return func(c *config) { c.route = f }
+}
+
+// WithSkipPaths the paths which are not logged, such as /healthz.
+func WithSkipPaths(paths ...string) Option {
+	return func(c *config) {
+		for _, p := range paths {
+			c.skipPaths[p] = struct{}{}
+		}
+	}
+}
+
+// WithSkipper the request which is not logged if f returns true.
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(c *config) { c.skip = f }
+}
+
+// WithLevel the level of the access entry by status, default DefaultLevel.
+func WithLevel(f func(status int) log.Level) Option {
+	return func(c *config) { c.level = f }
+}
+
+// WithRequestHeaders the request headers captured.
+func WithRequestHeaders(headers ...string) Option {
+	return func(c *config) { c.requestHeaders = headers }
+}
+
+// WithResponseHeaders the response headers captured.
+func WithResponseHeaders(headers ...string) Option {
+	return func(c *config) { c.responseHeaders = headers }
+}
+
+// WithRequestBody capture the request body up to limit bytes.
+func WithRequestBody(limit int) Option {
+	return func(c *config) { c.requestBody = limit }
+}
+
+// WithResponseBody capture the response body up to limit bytes.
+func WithResponseBody(limit int) Option {
+	return func(c *config) { c.responseBody = limit }
+}
+
+// WithBuffered buffer the entries below the level of the request, which are emitted
+// if the status is 5xx or the latency exceeds the threshold, see log.BeginBuffered.
+// threshold 0 means only emitted on 5xx.
+func WithBuffered(threshold time.Duration) Option {
+	return func(c *config) {
+		c.buffered = true
+		c.bufferedLatency = threshold
+	}
+}
+
+// DefaultLevel the level of the access entry, 5xx is error, 4xx is warn, others are info.
+func DefaultLevel(status int) log.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return log.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return log.WarnLevel
+	default:
+		return log.InfoLevel
+	}
+}
+
+func newRequestId() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestId the request id from the client should be printable and not too long.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware returns the middleware which propagates the request id, stores the
+// request-scoped logger in the context, and writes one access entry per request.
+func Middleware(l *log.Log, opts ...Option) func(http.Handler) http.Handler {
+	c := &config{
+		message:         "http access",
+		requestIdHeader: DefaultRequestIdHeader,
+		genRequestId:    newRequestId,
+		route:           func(r *http.Request) string { return r.URL.Path },
+		skipPaths:       make(map[string]struct{}),
+		level:           DefaultLevel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(c.requestIdHeader)
+			if !validRequestId(id) {
+				id = c.genRequestId()
+			}
+			w.Header().Set(c.requestIdHeader, id)
+			scoped := l.With(log.String("requestId", id))
+			ctx := ContextWithRequestId(r.Context(), id)
+			ctx = context.WithValue(ctx, loggerKey{}, scoped)
+			if c.buffered {
+				ctx = log.BeginBuffered(ctx, log.WithBufferedLatency(c.bufferedLatency))
+			}
+			r = r.WithContext(ctx)
+
+			_, skipped := c.skipPaths[r.URL.Path]
+			skipped = skipped || (c.skip != nil && c.skip(r))
+			if skipped {
+				next.ServeHTTP(w, r)
+				if c.buffered {
+					log.Finish(ctx, nil)
+				}
+				return
+			}
+
+			var reqBody []byte
+			if c.requestBody > 0 && r.Body != nil {
+				reqBody, r.Body = captureBody(r.Body, c.requestBody)
+			}
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK, limit: c.responseBody}
+			// the access entry is written even if the handler panics, the panic is propagated.
+			defer func() {
+				p := recover()
+				if p != nil && !rw.wroteHeader {
+					rw.status = http.StatusInternalServerError
+				}
+				latency := time.Since(start)
+				if c.buffered {
+					var err error
+					if rw.status >= http.StatusInternalServerError || p != nil {
+						err = errServerError
+					}
+					log.Finish(ctx, err)
+				}
+				fields := []log.Field{
+					log.String("method", r.Method),
+					log.String("route", c.route(r)),
+					log.Int("status", rw.status),
+					log.Int64("bytes", rw.bytes),
+					log.Duration("latency", latency),
+					log.String("remoteAddr", r.RemoteAddr),
+					log.String("userAgent", r.UserAgent()),
+				}
+				if len(c.requestHeaders) > 0 {
+					fields = append(fields, headerField("requestHeaders", r.Header, c.requestHeaders))
+				}
+				if len(c.responseHeaders) > 0 {
+					fields = append(fields, headerField("responseHeaders", w.Header(), c.responseHeaders))
+				}
+				if reqBody != nil {
+					fields = append(fields, log.ByteString("requestBody", reqBody))
+				}
+				if rw.body != nil {
+					fields = append(fields, log.ByteString("responseBody", rw.body.Bytes()))
+				}
+				level := c.level(rw.status)
+				if p != nil {
+					fields = append(fields, log.Any("panic", p))
+					level = max(level, log.ErrorLevel)
+				}
+				scoped.Logx(ctx, level, c.message, fields...)
+				if p != nil {
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// errServerError emits the buffered entries when the status is 5xx.
+var errServerError = errors.New("httplog: server error")
+
+func headerField(key string, h http.Header, names []string) log.Field {
+	fields := make([]log.Field, 0, len(names))
+	for _, name := range names {
+		if v := h.Values(name); len(v) > 0 {
+			fields = append(fields, log.String(name, strings.Join(v, ",")))
+		}
+	}
+	return log.Dict(key, fields...)
+}
+
+// captureBody read the body up to limit, and returns the body which replays the read bytes.
+func captureBody(body io.ReadCloser, limit int) ([]byte, io.ReadCloser) {
+	b, err := io.ReadAll(io.LimitReader(body, int64(limit)))
+	if err != nil {
+		return nil, body
+	}
+	return b, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), body), body}
+}
+
+// responseWriter records the status, the bytes written and the body up to limit.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	limit       int
+	body        *bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	if w.limit > 0 {
+		if w.body == nil {
+			w.body = &bytes.Buffer{}
+		}
+		if remain := w.limit - w.body.Len(); remain > 0 {
+			w.body.Write(p[:min(n, remain)])
+		}
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, such as the websocket upgrade,
+// the status is 101 if not written before hijacked.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httplog: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap returns the underlying ResponseWriter, used by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,219 @@
+package httplog_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/httplog"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func Test_Middleware_AccessEntry(t *testing.T) {
+	l, buf := newBufferLogger()
+	var scoped *log.Log
+	h := httplog.Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scoped = httplog.FromContext(r.Context())
+		if got := httplog.RequestIdFromContext(r.Context()); got != "abc" {
+			t.Errorf("want request id abc in context, got: %s", got)
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set(httplog.DefaultRequestIdHeader, "abc")
+	w := serve(h, r)
+	if got := w.Header().Get(httplog.DefaultRequestIdHeader); got != "abc" {
+		t.Errorf("want request id propagated, got: %s", got)
+	}
+	if scoped == nil {
+		t.Fatal("want scoped logger in context")
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"level=info", `msg="http access"`, "requestId=abc", "method=GET",
+		"route=/users/1", "status=200", "bytes=5", "latency=",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+
+	buf.Reset()
+	scoped.Infox("in handler")
+	if !strings.Contains(buf.String(), "requestId=abc") {
+		t.Errorf("want scoped logger with request id, got: %s", buf.String())
+	}
+}
+
+func Test_Middleware_GenerateRequestId(t *testing.T) {
+	l, buf := newBufferLogger()
+	h := httplog.Middleware(l, httplog.WithRequestIdGenerator(func() string { return "generated" }))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(httplog.DefaultRequestIdHeader, "bad id\n")
+	w := serve(h, r)
+	if got := w.Header().Get(httplog.DefaultRequestIdHeader); got != "generated" {
+		t.Errorf("want generated request id, got: %s", got)
+	}
+	if !strings.Contains(buf.String(), "requestId=generated") {
+		t.Errorf("want generated request id logged, got: %s", buf.String())
+	}
+}
+
+func Test_Middleware_LevelAndSkip(t *testing.T) {
+	l, buf := newBufferLogger()
+	h := httplog.Middleware(l, httplog.WithSkipPaths("/healthz"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			case "/panic":
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	serve(h, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("want skipped, got: %s", buf.String())
+	}
+	serve(h, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if !strings.Contains(buf.String(), "level=warn") || !strings.Contains(buf.String(), "status=404") {
+		t.Errorf("want warn on 4xx, got: %s", buf.String())
+	}
+	buf.Reset()
+	serve(h, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if !strings.Contains(buf.String(), "level=error") || !strings.Contains(buf.String(), "status=500") {
+		t.Errorf("want error on 5xx, got: %s", buf.String())
+	}
+}
+
+func Test_Middleware_CaptureBody(t *testing.T) {
+	l, buf := newBufferLogger()
+	h := httplog.Middleware(l,
+		httplog.WithRequestBody(4),
+		httplog.WithResponseBody(3),
+		httplog.WithRequestHeaders("Content-Type"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != "request body" {
+			t.Errorf("want the body replayed, got: %s", b)
+		}
+		_, _ = w.Write([]byte("response"))
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("request body"))
+	r.Header.Set("Content-Type", "text/plain")
+	w := serve(h, r)
+	if w.Body.String() != "response" {
+		t.Errorf("want the full response, got: %s", w.Body.String())
+	}
+	got := buf.String()
+	for _, want := range []string{"requestBody=requ", "responseBody=res\n", "bytes=8", "Content-Type=text/plain"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Middleware_Buffered(t *testing.T) {
+	l, buf := newBufferLogger(log.WithLevel("info"), log.WithCallerSkip(2))
+	h := httplog.Middleware(l, httplog.WithBuffered(0))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httplog.FromContext(r.Context()).DebugxContext(r.Context(), "debug detail")
+			if r.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	serve(h, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if strings.Contains(buf.String(), "debug detail") {
+		t.Errorf("want debug discarded on success, got: %s", buf.String())
+	}
+	buf.Reset()
+	serve(h, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	got := lines(buf.String())
+	if len(got) != 2 || !strings.Contains(got[0], `msg="debug detail"`) || !strings.Contains(got[1], "status=500") {
+		t.Errorf("want debug emitted before the access entry on 5xx, got: %q", got)
+	}
+}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func Test_Middleware_Panic(t *testing.T) {
+	l, buf := newBufferLogger()
+	h := httplog.Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("want the panic propagated, got: %v", p)
+			}
+		}()
+		serve(h, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+	got := buf.String()
+	for _, want := range []string{"level=error", "route=/panic", "status=500", "panic=boom"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Middleware_Hijack(t *testing.T) {
+	l, buf := newBufferLogger()
+	done := make(chan struct{})
+	h := httplog.Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = brw.Flush()
+	}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("want 101, got: %d", resp.StatusCode)
+	}
+	<-done
+	if got := buf.String(); !strings.Contains(got, "route=/ws") || !strings.Contains(got, "status=101") {
+		t.Errorf("want hijacked request logged, got: %s", got)
+	}
+}
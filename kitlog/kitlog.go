@@ -0,0 +1,94 @@
+// Package kitlog provides the go-kit log.Logger backed by *log.Log.
+package kitlog
+
+import (
+	"context"
+	"fmt"
+
+	gokitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/things-go/log"
+)
+
+var _ gokitlog.Logger = (*Logger)(nil)
+
+// Option the option of the logger.
+type Option func(l *Logger)
+
+// WithMessageKey the key of the message in the keyvals, default "msg".
+func WithMessageKey(key string) Option {
+	return func(l *Logger) { l.messageKey = key }
+}
+
+// WithDefaultLevel the level of the keyvals without the level key of go-kit, default info.
+func WithDefaultLevel(lv log.Level) Option {
+	return func(l *Logger) { l.defaultLevel = lv }
+}
+
+// Logger implements log.Logger of go-kit, the level of go-kit/log/level is mapped onto
+// the level, and the value of message key is the message of the entry.
+//
+//	logger := kitlog.New(l.Named("kit"))
+//	level.Info(logger).Log("msg", "started", "addr", addr)
+type Logger struct {
+	log          *log.Log
+	messageKey   string
+	defaultLevel log.Level
+}
+
+// New returns the go-kit logger.
+func New(l *log.Log, opts ...Option) *Logger {
+	kl := &Logger{
+		log:          l,
+		messageKey:   "msg",
+		defaultLevel: log.InfoLevel,
+	}
+	for _, opt := range opts {
+		opt(kl)
+	}
+	return kl
+}
+
+// Log implements log.Logger of go-kit.
+func (l *Logger) Log(keyvals ...any) error {
+	lv, msg := l.defaultLevel, ""
+	kvs := make([]any, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 >= len(keyvals) {
+			// the dangling key is handled by the sweeten fields.
+			kvs = append(kvs, keyvals[i])
+			break
+		}
+		k, v := keyvals[i], keyvals[i+1]
+		switch {
+		case k == level.Key():
+			if value, ok := v.(level.Value); ok {
+				lv = toLevel(value)
+				continue
+			}
+		case k == l.messageKey:
+			msg = fmt.Sprint(v)
+			continue
+		}
+		if s, ok := k.(fmt.Stringer); ok {
+			k = s.String()
+		}
+		kvs = append(kvs, k, v)
+	}
+	l.log.Logw(context.Background(), lv, msg, kvs...)
+	return nil
+}
+
+func toLevel(v level.Value) log.Level {
+	switch v.String() {
+	case level.DebugValue().String():
+		return log.DebugLevel
+	case level.WarnValue().String():
+		return log.WarnLevel
+	case level.ErrorValue().String():
+		return log.ErrorLevel
+	default:
+		return log.InfoLevel
+	}
+}
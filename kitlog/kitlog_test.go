@@ -0,0 +1,54 @@
+package kitlog_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	gokitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/kitlog"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Logger(t *testing.T) {
+	l, buf := newBufferLogger()
+	logger := gokitlog.With(kitlog.New(l), "component", "http")
+
+	_ = level.Warn(logger).Log("msg", "slow", "latency", "2s")
+	assertContains(t, buf.String(), "level=warn", "msg=slow", "component=http", "latency=2s")
+
+	buf.Reset()
+	_ = logger.Log("event", "started")
+	assertContains(t, buf.String(), "level=info", "event=started")
+
+	buf.Reset()
+	_ = level.Error(logger).Log("err", errors.New("refused"))
+	assertContains(t, buf.String(), "level=error", "err=refused")
+
+	buf.Reset()
+	_ = kitlog.New(l, kitlog.WithDefaultLevel(log.DebugLevel), kitlog.WithMessageKey("message")).
+		Log("message", "hello")
+	assertContains(t, buf.String(), "level=debug", "msg=hello")
+}
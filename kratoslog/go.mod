@@ -0,0 +1,17 @@
+module github.com/things-go/log/kratoslog
+
+go 1.21
+
+require (
+	github.com/go-kratos/kratos/v2 v2.7.3
+	github.com/things-go/log v0.0.0-20261019071040-f877d589fc7f
+)
+
+require (
+	github.com/klauspost/compress v1.17.10 // indirect
+	github.com/natefinch/lumberjack v2.0.0+incompatible // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
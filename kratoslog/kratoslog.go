@@ -0,0 +1,60 @@
+// Package kratoslog provides the Kratos log.Logger backed by *log.Log.
+package kratoslog
+
+import (
+	"context"
+	"fmt"
+
+	kratos "github.com/go-kratos/kratos/v2/log"
+
+	"github.com/things-go/log"
+)
+
+var _ kratos.Logger = (*Logger)(nil)
+
+// Logger implements log.Logger of Kratos, the value of log.DefaultMessageKey of Kratos
+// is the message of the entry.
+//
+//	logger := kratoslog.New(l.Named("kratos"))
+//	app := kratos.New(kratos.Logger(logger))
+type Logger struct {
+	log *log.Log
+}
+
+// New returns the Kratos logger.
+func New(l *log.Log) *Logger {
+	return &Logger{log: l}
+}
+
+// Log implements log.Logger of Kratos.
+func (l *Logger) Log(level kratos.Level, keyvals ...any) error {
+	msg := ""
+	kvs := make([]any, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) && keyvals[i] == kratos.DefaultMessageKey {
+			msg = fmt.Sprint(keyvals[i+1])
+			continue
+		}
+		kvs = append(kvs, keyvals[i:min(i+2, len(keyvals))]...)
+	}
+	l.log.Logw(context.Background(), toLevel(level), msg, kvs...)
+	return nil
+}
+
+// Sync flushes any buffered log entries.
+func (l *Logger) Sync() error { return l.log.Sync() }
+
+func toLevel(lv kratos.Level) log.Level {
+	switch lv {
+	case kratos.LevelDebug:
+		return log.DebugLevel
+	case kratos.LevelWarn:
+		return log.WarnLevel
+	case kratos.LevelError:
+		return log.ErrorLevel
+	case kratos.LevelFatal:
+		return log.FatalLevel
+	default:
+		return log.InfoLevel
+	}
+}
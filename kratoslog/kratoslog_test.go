@@ -0,0 +1,47 @@
+package kratoslog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	kratos "github.com/go-kratos/kratos/v2/log"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/kratoslog"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Logger(t *testing.T) {
+	l, buf := newBufferLogger()
+	helper := kratos.NewHelper(kratos.With(kratoslog.New(l), "service", "user"))
+
+	helper.Infow("msg", "started", "port", 8000)
+	assertContains(t, buf.String(), "level=info", "msg=started", "service=user", "port=8000")
+
+	buf.Reset()
+	helper.Errorf("failed: %s", "timeout")
+	assertContains(t, buf.String(), "level=error", `msg="failed: timeout"`)
+
+	buf.Reset()
+	helper.Debug("detail")
+	assertContains(t, buf.String(), "level=debug", "msg=detail")
+}
@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
@@ -129,6 +130,12 @@ func (l *Log) With(fields ...Field) *Log {
 	}
 }
 
+// Withw creates a child logger with loosely-typed key-value pairs, same as the methods ending in "w",
+// such as the adapters of the other logging interfaces.
+func (l *Log) Withw(keysAndValues ...any) *Log {
+	return l.With(l.appendSweetenFields(nil, keysAndValues)...)
+}
+
 // Named adds a sub-scope to the logger's name. See Log.Named for details.
 func (l *Log) Named(name string) *Log {
 	return &Log{
@@ -139,11 +146,32 @@ func (l *Log) Named(name string) *Log {
 	}
 }
 
+// WithOptions clones the logger and applies the zap options to the underlying zap logger,
+// such as zap.AddCallerSkip for the adapters of the other logging interfaces.
+func (l *Log) WithOptions(opts ...zap.Option) *Log {
+	return &Log{
+		log:   l.log.WithOptions(opts...),
+		level: l.level,
+		fn:    l.fn,
+		ctx:   l.ctx,
+	}
+}
+
 // Sync flushes any buffered log entries.
 func (l *Log) Sync() error {
 	return l.log.Sync()
 }
 
+// Close flushes any buffered log entries, and stops the background goroutines
+// and closes the connections of the adapters, such as the spill replayer of the net adapter.
+// the logger and all the child loggers should not be used after closed.
+func (l *Log) Close() error {
+	if c, ok := l.log.Core().(io.Closer); ok {
+		return c.Close()
+	}
+	return l.log.Sync()
+}
+
 func (l *Log) Log(ctx context.Context, level Level, args ...any) {
 	l.Logx(ctx, level, formatMessage("", args))
 }
@@ -153,33 +181,76 @@ func (l *Log) Logf(ctx context.Context, level Level, template string, args ...an
 }
 
 func (l *Log) Logw(ctx context.Context, level Level, msg string, keysAndValues ...any) {
-	if !l.level.Enabled(level) {
+	logger := l.logger(ctx, level)
+	if logger == nil {
+		return
+	}
+	ce := logger.Check(level, msg)
+	if ce == nil {
 		return
 	}
 	fc := poolGet()
 	defer poolPut(fc)
-	for _, f := range l.fn {
-		fc.Fields = append(fc.Fields, f(ctx))
-	}
+	fc.Fields = l.appendValuerFields(ctx, level, fc.Fields)
 	fc.Fields = l.appendSweetenFields(fc.Fields, keysAndValues)
-	l.log.Log(level, msg, fc.Fields...)
+	ce.Write(fc.Fields...)
 }
 
 func (l *Log) Logx(ctx context.Context, level Level, msg string, fields ...Field) {
-	if !l.level.Enabled(level) {
+	logger := l.logger(ctx, level)
+	if logger == nil {
 		return
 	}
 	if len(l.fn) == 0 {
-		l.log.Log(level, msg, fields...)
+		logger.Log(level, msg, fields...)
 	} else {
+		// evaluate Valuer only when the entry will be written actually,
+		// the entry may be filtered by sampler or core.
+		ce := logger.Check(level, msg)
+		if ce == nil {
+			return
+		}
 		fc := poolGet()
 		defer poolPut(fc)
-		for _, f := range l.fn {
-			fc.Fields = append(fc.Fields, f(ctx))
-		}
+		fc.Fields = l.appendValuerFields(ctx, level, fc.Fields)
 		fc.Fields = append(fc.Fields, fields...)
-		l.log.Log(level, msg, fc.Fields...)
+		ce.Write(fc.Fields...)
+	}
+}
+
+// logger returns the zap logger which the entry is written to, nil if the level is disabled.
+// the entry below the level is buffered if the ctx is buffered, see BeginBuffered.
+func (l *Log) logger(ctx context.Context, level Level) *zap.Logger {
+	if l.level.Enabled(level) {
+		return l.log
+	}
+	if rb := bufferFromContext(ctx); rb != nil {
+		if logger := rb.logger(l.log); logger != nil {
+			return logger
+		}
+	}
+	if core := l.log.Core(); isRecording(core) && core.Enabled(level) {
+		// the flight recorder records the entries below the level.
+		return l.log
+	}
+	return nil
+}
+
+// appendValuerFields evaluate all the Valuer with the context which carry the entry level,
+// see LevelFromContext, the field which type is zapcore.SkipType will be dropped.
+func (l *Log) appendValuerFields(ctx context.Context, level Level, fields []Field) []Field {
+	if len(l.fn) == 0 {
+		return fields
 	}
+	ctx = newLevelContext(ctx, level)
+	for _, f := range l.fn {
+		field := callValuer(ctx, f)
+		if field.Type == zapcore.SkipType {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
 }
 
 // ****** named after the log level or ending in "Context" for log.Print-style logging
@@ -2,6 +2,7 @@ package log
 
 import (
 	"io"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 )
@@ -10,22 +11,40 @@ import (
 type Config struct {
 	// Level 日志等级, debug,info,warn,error,dpanic,panic,fatal, 默认warn
 	Level string `yaml:"level" json:"level"`
-	// Format: 编码格式: json,console 默认json
+	// Format: 编码格式: json,console,logfmt,pretty 默认json
 	Format string `yaml:"format" json:"format"`
+	// Profile 日志格式规范, ecs,gcp,datadog 默认空, 使用默认的 EncoderConfig
+	// 如果配置 EncoderConfig, 则仅改写字段
+	Profile string `yaml:"profile" json:"profile"`
 	// 编码器类型, 默认: LowercaseLevelEncoder
 	// LowercaseLevelEncoder: 小写编码器
 	// LowercaseColorLevelEncoder: 小写编码器带颜色
 	// CapitalLevelEncoder: 大写编码器
 	// CapitalColorLevelEncoder: 大写编码器带颜色
 	EncodeLevel string `yaml:"encodeLevel" json:"encodeLevel"`
-	// Adapter 输出适配器, file,console,multi,custom,file-custom,console-custom,multi-custom 默认 console
+	// Adapter 输出适配器, file,console,multi,custom,file-custom,console-custom,multi-custom,syslog,journald,net,loki,elasticsearch,forward 默认 console
 	Adapter string `yaml:"adapter" json:"adapter"`
+	// DuplicateKey 重复key处理策略(Valuer, With, 调用处的字段), keep-last,keep-first,rename,error 默认不处理
+	DuplicateKey string `yaml:"duplicateKey" json:"duplicateKey"`
 	// Stack 是否使能栈调试输出, 默认false
 	Stack bool `yaml:"stack" json:"stack"`
 	// AddCaller add caller
 	AddCaller bool `yaml:"addCaller" json:"addCaller"`
 	// CallerSkip call skip if AddCaller enabled
 	CallerSkip int `yaml:"callerSkip" json:"callerSkip"`
+	// FlightRecorder 如果配置该项, 则低于日志等级的日志将保留在内存中, 记录错误时输出
+	FlightRecorder *FlightRecorder `yaml:"-" json:"-"`
+	// Fallback 输出失败时依次尝试的备用输出, stderr,stdout 或文件路径, 默认空, 不使用备用
+	// 如 file 写失败(磁盘满,权限)时使用 stderr, loki,elasticsearch,forward 重试失败的批次同样写入备用输出
+	Fallback []string `yaml:"fallback" json:"fallback"`
+	// WriteRetries 输出临时失败(超时,EAGAIN等)时的重试次数, 默认0 不重试
+	WriteRetries int `yaml:"writeRetries" json:"writeRetries"`
+	// RetryBackoff 重试最小退避时间, 默认10ms, 每次失败加倍
+	RetryBackoff time.Duration `yaml:"retryBackoff" json:"retryBackoff"`
+	// ErrorHandler 输出失败时的回调, 参数为失败的输出名及错误
+	ErrorHandler SinkErrorHandler `yaml:"-" json:"-"`
+	// Metrics 日志管道指标(条数,字节,耗时,错误,丢弃,切割), 如 promlog.NewCollector(), 默认空, 不统计
+	Metrics Metrics `yaml:"-" json:"-"`
 	// Path 日志保存路径, 默认 empty, 即当前路径
 	Path string `yaml:"path" json:"path"`
 	// Writer 输出
@@ -48,6 +67,35 @@ type Config struct {
 	LocalTime bool `yaml:"localTime" json:"localTime"`
 	// Compress 是否使用gzip压缩文件, 采用默认不压缩
 	Compress bool `yaml:"compress" json:"compress"`
+	// RotateCompress 文件切割后的压缩格式, gzip,zstd 默认空, 不压缩, 建议不与 Compress 同时使用
+	RotateCompress string `yaml:"rotateCompress" json:"rotateCompress"`
+	// RotateCompressLevel 压缩等级, 默认0 使用压缩格式的默认等级
+	RotateCompressLevel int `yaml:"rotateCompressLevel" json:"rotateCompressLevel"`
+	// ArchiveDir 文件切割(及压缩)后移动到的归档目录, 默认空, 不移动
+	// 归档目录中的文件不受 MaxAge 及 MaxBackups 限制, zstd 压缩的备份由本库按 MaxAge 及 MaxBackups 清理
+	ArchiveDir string `yaml:"archiveDir" json:"archiveDir"`
+	// RotateHooks 文件切割后的处理, 在 RotateCompress 及 ArchiveDir 之后依次执行, 如上传到对象存储
+	RotateHooks []RotateHook `yaml:"-" json:"-"`
+	// MaxTotalSize 日志文件(含备份)总大小上限(MB), 超出时删除最旧的备份, 默认0 不限制
+	MaxTotalSize int `yaml:"maxTotalSize" json:"maxTotalSize"`
+	// MinFreeSpace 磁盘最小剩余空间(MB), 低于该值时文件输出丢弃 debug,info 日志并输出警告, 默认0 不限制
+	MinFreeSpace int `yaml:"minFreeSpace" json:"minFreeSpace"`
+	// JanitorInterval MaxTotalSize 及 MinFreeSpace 检查间隔, 默认1m
+	JanitorInterval time.Duration `yaml:"janitorInterval" json:"janitorInterval"`
+
+	// Syslog 当 adapter=syslog 使用
+	Syslog SyslogConfig `yaml:"syslog" json:"syslog"`
+	// Journald 当 adapter=journald 使用
+	Journald JournaldConfig `yaml:"journald" json:"journald"`
+	// Net 当 adapter=net 使用
+	Net NetConfig `yaml:"net" json:"net"`
+	// Shipper 当 adapter=loki,elasticsearch 使用
+	Shipper ShipperConfig `yaml:"shipper" json:"shipper"`
+	// Forward 当 adapter=forward 使用
+	Forward ForwardConfig `yaml:"forward" json:"forward"`
+
+	// closers 由 New 设置, 适配器的后台任务及连接, 在 Log.Close 时关闭
+	closers *closers
 }
 
 // Option An Option configures a Log.
@@ -66,12 +114,19 @@ func WithLevel(level string) Option {
 }
 
 // WithFormat with format
-// json,console
+// json,console,logfmt,pretty
 // 默认json
 func WithFormat(format string) Option {
 	return func(c *Config) { c.Format = format }
 }
 
+// WithProfile with profile
+// ecs,gcp,datadog
+// 默认空, 使用默认的 EncoderConfig
+func WithProfile(profile string) Option {
+	return func(c *Config) { c.Profile = profile }
+}
+
 // WithEncodeLevel with EncodeLevel
 // LowercaseLevelEncoder: 小写编码器
 // LowercaseColorLevelEncoder: 小写编码器带颜色
@@ -88,7 +143,7 @@ func WithEncoderConfig(encoderConfig *zapcore.EncoderConfig) Option {
 }
 
 // WithAdapter with adapter
-// file,console,multi,custom,file-custom,console-custom,multi-custom
+// file,console,multi,custom,file-custom,console-custom,multi-custom,syslog,journald,net,loki,elasticsearch,forward
 // writer: 当 adapter=custom使用,如果为writer为空,将使用os.Stdout
 // 默认 console
 func WithAdapter(adapter string, writer ...io.Writer) Option {
@@ -98,6 +153,13 @@ func WithAdapter(adapter string, writer ...io.Writer) Option {
 	}
 }
 
+// WithDuplicateKey with duplicate key policy
+// keep-last,keep-first,rename,error
+// 默认不处理
+func WithDuplicateKey(policy string) Option {
+	return func(c *Config) { c.DuplicateKey = policy }
+}
+
 // WithStack with stack
 // Stack 是否使能栈调试输出, 默认false
 func WithStack(stack bool) Option {
@@ -115,6 +177,33 @@ func WithCallerSkip(skip int) Option {
 	return func(c *Config) { c.CallerSkip = skip }
 }
 
+// WithFlightRecorder with flight recorder
+// 低于日志等级的日志将保留在内存中, 记录错误或调用 FlightRecorder.Dump 时输出
+func WithFlightRecorder(r *FlightRecorder) Option {
+	return func(c *Config) { c.FlightRecorder = r }
+}
+
+// WithFallback with fallback sinks
+// stderr,stdout 或文件路径, 输出失败时依次尝试
+func WithFallback(sinks ...string) Option {
+	return func(c *Config) { c.Fallback = sinks }
+}
+
+// WithWriteRetry with write retries and backoff
+// 输出临时失败(超时,EAGAIN等)时的重试次数及最小退避时间, 默认不重试
+func WithWriteRetry(retries int, backoff time.Duration) Option {
+	return func(c *Config) {
+		c.WriteRetries = retries
+		c.RetryBackoff = backoff
+	}
+}
+
+// WithErrorHandler with sink error handler
+// 输出失败时的回调, 参数为失败的输出名及错误
+func WithErrorHandler(h SinkErrorHandler) Option {
+	return func(c *Config) { c.ErrorHandler = h }
+}
+
 // WithPath with path
 // 日志保存路径, 默认 empty, 即当前路径
 func WithPath(path string) Option {
@@ -158,3 +247,85 @@ func WithEnableLocalTime() Option {
 func WithEnableCompress() Option {
 	return func(c *Config) { c.Compress = true }
 }
+
+// WithRotateCompress with rotate compress
+// 文件切割后的压缩格式 gzip,zstd 及压缩等级, 等级0 使用压缩格式的默认等级
+func WithRotateCompress(format string, level int) Option {
+	return func(c *Config) {
+		c.RotateCompress = format
+		c.RotateCompressLevel = level
+	}
+}
+
+// WithArchiveDir with archive dir
+// 文件切割(及压缩)后移动到的归档目录
+func WithArchiveDir(dir string) Option {
+	return func(c *Config) { c.ArchiveDir = dir }
+}
+
+// WithRotateHooks with rotate hooks
+// 文件切割后的处理, 在 RotateCompress 及 ArchiveDir 之后依次执行, 如上传到对象存储
+func WithRotateHooks(hooks ...RotateHook) Option {
+	return func(c *Config) { c.RotateHooks = append(c.RotateHooks, hooks...) }
+}
+
+// WithMaxTotalSize with max total size
+// 日志文件(含备份)总大小上限(MB), 超出时删除最旧的备份, 默认0 不限制
+func WithMaxTotalSize(maxTotalSize int) Option {
+	return func(c *Config) { c.MaxTotalSize = maxTotalSize }
+}
+
+// WithMinFreeSpace with min free space
+// 磁盘最小剩余空间(MB), 低于该值时文件输出丢弃 debug,info 日志并输出警告, 默认0 不限制
+func WithMinFreeSpace(minFreeSpace int) Option {
+	return func(c *Config) { c.MinFreeSpace = minFreeSpace }
+}
+
+// WithJanitorInterval with janitor interval
+// MaxTotalSize 及 MinFreeSpace 检查间隔, 默认1m
+func WithJanitorInterval(interval time.Duration) Option {
+	return func(c *Config) { c.JanitorInterval = interval }
+}
+
+/******************************** syslog **************************************/
+
+// WithSyslog with syslog config, used when adapter=syslog
+func WithSyslog(sc SyslogConfig) Option {
+	return func(c *Config) { c.Syslog = sc }
+}
+
+/******************************** journald **************************************/
+
+// WithJournald with journald config, used when adapter=journald
+func WithJournald(jc JournaldConfig) Option {
+	return func(c *Config) { c.Journald = jc }
+}
+
+/******************************** net **************************************/
+
+// WithNet with net config, used when adapter=net
+func WithNet(nc NetConfig) Option {
+	return func(c *Config) { c.Net = nc }
+}
+
+/******************************** shipper **************************************/
+
+// WithShipper with shipper config, used when adapter=loki,elasticsearch
+func WithShipper(sc ShipperConfig) Option {
+	return func(c *Config) { c.Shipper = sc }
+}
+
+/******************************** forward **************************************/
+
+// WithForward with fluent forward config, used when adapter=forward
+func WithForward(fc ForwardConfig) Option {
+	return func(c *Config) { c.Forward = fc }
+}
+
+/******************************** metrics **************************************/
+
+// WithMetrics with metrics of the logging pipeline
+// 如 promlog.NewCollector(), 默认空, 不统计
+func WithMetrics(m Metrics) Option {
+	return func(c *Config) { c.Metrics = m }
+}
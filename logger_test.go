@@ -79,6 +79,11 @@ func Test_LoggerWith(t *testing.T) {
 		Debug("debug with")
 }
 
+func Test_LoggerWithw(t *testing.T) {
+	log.Withw("string", "bb", log.Int16("int16", 100)).
+		Debug("debug withw")
+}
+
 func Test_LoggerNamed(t *testing.T) {
 	log.Named("another").Debug("debug named")
 }
@@ -0,0 +1,72 @@
+// Package logrlog provides the logr.LogSink backed by *log.Log, such as for controller-runtime.
+package logrlog
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+
+	"github.com/things-go/log"
+)
+
+var (
+	_ logr.LogSink          = (*LogSink)(nil)
+	_ logr.CallDepthLogSink = (*LogSink)(nil)
+)
+
+// LogSink implements logr.LogSink, the verbosity V(n) of logr is mapped onto the level -n,
+// so V(0) is info, V(1) is debug, and V(2) and above is below debug, which is enabled by
+// the lower level of *log.Log, such as log.SetLevel(-2).
+type LogSink struct {
+	log *log.Log
+}
+
+// New returns the logr.Logger.
+//
+//	ctrl.SetLogger(logrlog.New(l.Named("controller")))
+func New(l *log.Log) logr.Logger {
+	return logr.New(NewLogSink(l))
+}
+
+// NewLogSink returns the logr.LogSink.
+func NewLogSink(l *log.Log) *LogSink {
+	return &LogSink{log: l}
+}
+
+// Init implements logr.LogSink, the call frames of logr and the LogSink itself are skipped
+// in addition to the CallerSkip of the logger when the caller is added.
+func (s *LogSink) Init(info logr.RuntimeInfo) {
+	s.log = s.log.WithOptions(zap.AddCallerSkip(info.CallDepth + 1))
+}
+
+// Enabled implements logr.LogSink.
+func (s *LogSink) Enabled(level int) bool { return s.log.V(-level) }
+
+// Info implements logr.LogSink.
+func (s *LogSink) Info(level int, msg string, keysAndValues ...any) {
+	s.log.Logw(context.Background(), log.Level(-level), msg, keysAndValues...)
+}
+
+// Error implements logr.LogSink, the error is added as the error field.
+func (s *LogSink) Error(err error, msg string, keysAndValues ...any) {
+	if err != nil {
+		keysAndValues = append([]any{log.Err(err)}, keysAndValues...)
+	}
+	s.log.Logw(context.Background(), log.ErrorLevel, msg, keysAndValues...)
+}
+
+// WithValues implements logr.LogSink.
+func (s *LogSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &LogSink{log: s.log.Withw(keysAndValues...)}
+}
+
+// WithName implements logr.LogSink.
+func (s *LogSink) WithName(name string) logr.LogSink {
+	return &LogSink{log: s.log.Named(name)}
+}
+
+// WithCallDepth implements logr.CallDepthLogSink.
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	return &LogSink{log: s.log.WithOptions(zap.AddCallerSkip(depth))}
+}
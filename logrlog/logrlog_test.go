@@ -0,0 +1,79 @@
+package logrlog_test
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/logrlog"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_LogSink(t *testing.T) {
+	l, buf := newBufferLogger()
+	logger := logrlog.New(l).WithName("ctrl").WithValues("controller", "pod")
+
+	logger.Info("reconciled", "name", "a")
+	assertContains(t, buf.String(), "level=info", "logger=ctrl", "msg=reconciled", "controller=pod", "name=a")
+
+	buf.Reset()
+	logger.V(1).Info("detail")
+	assertContains(t, buf.String(), "level=debug", "msg=detail")
+
+	buf.Reset()
+	logger.V(2).Info("verbose")
+	if buf.Len() != 0 {
+		t.Errorf("want V(2) disabled, got: %s", buf.String())
+	}
+	l.SetLevel(-2)
+	if !logger.V(2).Enabled() {
+		t.Error("want V(2) enabled by the lower level")
+	}
+
+	buf.Reset()
+	logger.Error(errors.New("conflict"), "failed", "retry", true)
+	assertContains(t, buf.String(), "level=error", "msg=failed", "error=conflict", "retry=true")
+}
+
+func helper(logger logr.Logger) {
+	logger.WithCallDepth(1).Info("helper")
+}
+
+func Test_LogSink_Caller(t *testing.T) {
+	// the caller skip 1 reports the caller of *log.Log.
+	l, buf := newBufferLogger(log.WithAddCaller(true), log.WithCallerSkip(1))
+	logger := logrlog.New(l)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("direct")
+	assertContains(t, buf.String(), "logrlog_test.go:"+strconv.Itoa(line+1)+" ")
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	helper(logger)
+	assertContains(t, buf.String(), "logrlog_test.go:"+strconv.Itoa(line+1)+" ")
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// drop reason defined, which is passed to Metrics.Dropped.
+const (
+	DropQueueFull  = "queue_full"  // the queue of the async adapter is full, such as loki, forward
+	DropSendFailed = "send_failed" // the async adapter gives up after the retries
+	DropSinkFailed = "sink_failed" // the sink and all the fallbacks failed to write
+	DropDiskLow    = "disk_low"    // the disk free space is low, see MinFreeSpace
+)
+
+// sink name of the async adapters defined, which is passed to Metrics.
+const (
+	SinkLoki          = AdapterLoki
+	SinkElasticsearch = AdapterElasticsearch
+	SinkForward       = AdapterForward
+)
+
+// Metrics the metrics of the logging pipeline, see the promlog package for Prometheus.
+// the methods are called on the logging hot path, which should be fast and concurrency safe.
+type Metrics interface {
+	// Entry the entry is written, by the level and the logger name.
+	Entry(level zapcore.Level, logger string)
+	// Encoded the latency of encoding the entry.
+	Encoded(d time.Duration)
+	// Written the bytes written to the sink and the latency, which includes the retries.
+	Written(sink string, n int, d time.Duration)
+	// SinkError the sink failed to write.
+	SinkError(sink string)
+	// Dropped the entries dropped by the pipeline, see the drop reason.
+	Dropped(reason string, n int)
+	// Rotated the file sink is rotated.
+	Rotated(sink string)
+}
+
+// entryDropper the core which drops the entries by itself, such as the disk guard,
+// the dropped entries are reported by Metrics.Dropped instead of Metrics.Entry.
+type entryDropper interface {
+	drops(ent zapcore.Entry) bool
+}
+
+// metricsCore counts the entries accepted by the inner core, the entries dropped by
+// the inner core, see entryDropper, are reported by Metrics.Dropped only.
+type metricsCore struct {
+	core    zapcore.Core
+	metrics Metrics
+}
+
+func newMetricsCore(core zapcore.Core, m Metrics) zapcore.Core {
+	if m == nil {
+		return core
+	}
+	return &metricsCore{core: core, metrics: m}
+}
+
+func (c *metricsCore) Enabled(lvl zapcore.Level) bool { return c.core.Enabled(lvl) }
+
+func (c *metricsCore) With(fields []Field) zapcore.Core {
+	return &metricsCore{core: c.core.With(fields), metrics: c.metrics}
+}
+
+// Check adds the entryCounter only if the inner core accepts the entry.
+func (c *metricsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.core.Enabled(ent.Level) {
+		return ce
+	}
+	if ce != nil {
+		// the cores added by the inner core can not be told from the ones already added,
+		// such as by the hooks, so the entry is counted by Write.
+		return ce.AddCore(ent, c)
+	}
+	if ce = c.core.Check(ent, nil); ce != nil {
+		ce = ce.AddCore(ent, entryCounter{c.metrics})
+	}
+	return ce
+}
+
+// Write writes the entry directly, such as the entries dumped by the flight recorder.
+func (c *metricsCore) Write(ent zapcore.Entry, fields []Field) error {
+	if d, ok := c.core.(entryDropper); !ok || !d.drops(ent) {
+		c.metrics.Entry(ent.Level, ent.LoggerName)
+	}
+	return c.core.Write(ent, fields)
+}
+
+func (c *metricsCore) Sync() error { return c.core.Sync() }
+
+// entryCounter the core only counts the entry.
+type entryCounter struct{ metrics Metrics }
+
+func (entryCounter) Enabled(zapcore.Level) bool  { return true }
+func (c entryCounter) With([]Field) zapcore.Core { return c }
+func (c entryCounter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+func (c entryCounter) Write(ent zapcore.Entry, _ []Field) error {
+	c.metrics.Entry(ent.Level, ent.LoggerName)
+	return nil
+}
+func (entryCounter) Sync() error { return nil }
+
+// metricsEncoder measures the latency of encoding.
+type metricsEncoder struct {
+	zapcore.Encoder
+	metrics Metrics
+}
+
+func newMetricsEncoder(enc zapcore.Encoder, m Metrics) zapcore.Encoder {
+	if m == nil {
+		return enc
+	}
+	return &metricsEncoder{Encoder: enc, metrics: m}
+}
+
+func (e *metricsEncoder) Clone() zapcore.Encoder {
+	return &metricsEncoder{Encoder: e.Encoder.Clone(), metrics: e.metrics}
+}
+
+func (e *metricsEncoder) EncodeEntry(ent zapcore.Entry, fields []Field) (*buffer.Buffer, error) {
+	start := time.Now()
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	e.metrics.Encoded(time.Since(start))
+	return buf, err
+}
+
+// nopMetrics the Metrics does nothing, used when Metrics is not configured.
+type nopMetrics struct{}
+
+func (nopMetrics) Entry(zapcore.Level, string)        {}
+func (nopMetrics) Encoded(time.Duration)              {}
+func (nopMetrics) Written(string, int, time.Duration) {}
+func (nopMetrics) SinkError(string)                   {}
+func (nopMetrics) Dropped(string, int)                {}
+func (nopMetrics) Rotated(string)                     {}
+
+// toMetrics returns the Metrics of the config, nopMetrics if not configured.
+func toMetrics(c *Config) Metrics {
+	if c.Metrics == nil {
+		return nopMetrics{}
+	}
+	return c.Metrics
+}
@@ -0,0 +1,191 @@
+package log_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+// fakeMetrics records the metrics by name.
+type fakeMetrics struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	encoded int
+}
+
+func newFakeMetrics() *fakeMetrics { return &fakeMetrics{counts: make(map[string]int)} }
+
+func (m *fakeMetrics) add(key string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[key] += n
+}
+
+func (m *fakeMetrics) get(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[key]
+}
+
+func (m *fakeMetrics) Entry(level zapcore.Level, logger string) {
+	m.add("entry:"+level.String()+":"+logger, 1)
+}
+func (m *fakeMetrics) Encoded(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.encoded++
+}
+func (m *fakeMetrics) Written(sink string, n int, _ time.Duration) { m.add("bytes:"+sink, n) }
+func (m *fakeMetrics) SinkError(sink string)                       { m.add("error:"+sink, 1) }
+func (m *fakeMetrics) Dropped(reason string, n int)                { m.add("dropped:"+reason, n) }
+func (m *fakeMetrics) Rotated(sink string)                         { m.add("rotated:"+sink, 1) }
+
+func Test_Metrics_Sink(t *testing.T) {
+	m := newFakeMetrics()
+	w := &failWriter{}
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, w),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithLevel("info"),
+		log.WithMetrics(m),
+	)
+	l.Infox("hello")
+	l.Named("svc").Warnx("world")
+	l.Debugx("disabled")
+
+	if m.get("entry:info:") != 1 || m.get("entry:warn:svc") != 1 || m.get("entry:debug:") != 0 {
+		t.Errorf("unexpected entries: %v", m.counts)
+	}
+	if m.encoded != 2 {
+		t.Errorf("want 2 encoded, got: %d", m.encoded)
+	}
+	if got := m.get("bytes:" + log.SinkCustom); got != w.Len() {
+		t.Errorf("want %d bytes written, got: %d", w.Len(), got)
+	}
+}
+
+func Test_Metrics_Fallback(t *testing.T) {
+	m := newFakeMetrics()
+	fallback := filepath.Join(t.TempDir(), "fallback.log")
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, &failWriter{n: 1, err: syscall.ENOSPC}),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithFallback(fallback),
+		log.WithMetrics(m),
+	)
+	l.Warnx("disk full")
+
+	b, err := os.ReadFile(fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.get("error:"+log.SinkCustom) != 1 || m.get("bytes:"+fallback) != len(b) {
+		t.Errorf("want custom failed and written to fallback, got: %v", m.counts)
+	}
+
+	// all the sinks failed.
+	m = newFakeMetrics()
+	l = log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, &failWriter{n: -1, err: syscall.ENOSPC}),
+		log.WithFallback("/nonexistent/dir/fallback.log"),
+		log.WithMetrics(m),
+	)
+	l.Warnx("disk full")
+	if m.get("dropped:"+log.DropSinkFailed) != 1 || m.get("error:/nonexistent/dir/fallback.log") != 1 {
+		t.Errorf("want dropped, got: %v", m.counts)
+	}
+}
+
+func Test_Metrics_DiskLow(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []log.Option
+		hook bool
+	}{
+		{"check", nil, false},
+		{"hooks", nil, true},
+		{"write", []log.Option{log.WithDuplicateKey(log.DuplicateKeyKeepLast)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeMetrics()
+			l := log.NewLogger(append([]log.Option{
+				log.WithFormat(log.FormatLogfmt),
+				log.WithAdapter(log.AdapterFile),
+				log.WithPath(t.TempDir()),
+				log.WithFilename("app.log"),
+				log.WithLevel("info"),
+				log.WithMinFreeSpace(1 << 40), // always low
+				log.WithMetrics(m),
+			}, tt.opts...)...)
+			defer l.Close()
+			if tt.hook {
+				l = l.WithHooks(func(zapcore.Entry, []log.Field) {})
+			}
+			l.Infox("dropped info")
+			l.Warnx("kept warn")
+			if m.get("dropped:"+log.DropDiskLow) != 1 || m.get("bytes:"+log.SinkFile) == 0 {
+				t.Errorf("want info dropped and warn written, got: %v", m.counts)
+			}
+			if m.get("entry:info:") != 0 || m.get("entry:warn:") != 1 {
+				t.Errorf("want only the written entry counted, got: %v", m.counts)
+			}
+		})
+	}
+}
+
+func Test_Metrics_DiskLow_Multi(t *testing.T) {
+	m := newFakeMetrics()
+	buf := &bytes.Buffer{}
+	dir := t.TempDir()
+	l := log.NewLogger(
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterMultiCustom, buf),
+		log.WithPath(dir),
+		log.WithFilename("app.log"),
+		log.WithLevel("info"),
+		log.WithMinFreeSpace(1<<40), // always low
+		log.WithMetrics(m),
+	)
+	defer l.Close()
+	l = l.With(log.String("app", "test")).WithHooks(func(zapcore.Entry, []log.Field) {})
+
+	l.Infox("console only")
+	l.Warnx("both")
+	_ = l.Sync()
+	if m.get("dropped:"+log.DropDiskLow) != 1 {
+		t.Errorf("want info dropped by the file sink, got: %v", m.counts)
+	}
+	// the entry written by the console sink is counted, though the file sink drops it.
+	if m.get("entry:info:") != 1 || m.get("entry:warn:") != 1 {
+		t.Errorf("want the entries counted once, got: %v", m.counts)
+	}
+	if got := buf.String(); !strings.Contains(got, "console only") || !strings.Contains(got, "both") {
+		t.Errorf("want both entries written to the console, got: %s", got)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "app.log")); strings.Contains(string(b), "console only") {
+		t.Errorf("want the info entry dropped by the file sink, got: %s", b)
+	}
+}
+
+func Test_Metrics_Rotated(t *testing.T) {
+	m := newFakeMetrics()
+	l, rotated := rotateLogger(t, log.WithMetrics(m))
+	line := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		l.Warnx(line)
+	}
+	waitRotated(t, rotated)
+	if m.get("rotated:"+log.SinkFile) != 1 {
+		t.Errorf("want rotated once, got: %v", m.counts)
+	}
+}
@@ -9,7 +9,7 @@ import (
 var fieldPool = &sync.Pool{
 	New: func() any {
 		return &fieldContainer{
-			make([]zap.Field, 0, 32),
+			Fields: make([]zap.Field, 0, 32),
 		}
 	},
 }
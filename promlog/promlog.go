@@ -0,0 +1,199 @@
+// Package promlog provides the Prometheus collector of the logging pipeline metrics.
+//
+//	c := promlog.NewCollector()
+//	prometheus.MustRegister(c)
+//	l := log.NewLogger(log.WithMetrics(c))
+package promlog
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+var (
+	_ log.Metrics          = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// DefaultBuckets the default buckets of the latency histograms, from 10µs to about 2.6s.
+var DefaultBuckets = prometheus.ExponentialBuckets(0.00001, 4, 10)
+
+// Option the option of Collector.
+type Option func(c *config)
+
+type config struct {
+	namespace   string
+	buckets     []float64
+	constLabels prometheus.Labels
+	loggerLabel bool
+}
+
+// WithNamespace the namespace of the metrics, default empty, the metrics are named log_*.
+func WithNamespace(namespace string) Option {
+	return func(c *config) { c.namespace = namespace }
+}
+
+// WithBuckets the buckets of the latency histograms, default DefaultBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) { c.buckets = buckets }
+}
+
+// WithConstLabels the const labels of the metrics, such as the service name.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) { c.constLabels = labels }
+}
+
+// WithLoggerLabel whether log_entries_total has the logger label, default true.
+// the label is the logger name, such as set by Named, the names built from
+// the unbounded values, such as the request path, should disable it.
+func WithLoggerLabel(enabled bool) Option {
+	return func(c *config) { c.loggerLabel = enabled }
+}
+
+// Collector implements log.Metrics and prometheus.Collector, the metrics:
+//
+//	log_entries_total{level,logger}        entries written, the logger label is optional, see WithLoggerLabel
+//	log_encode_duration_seconds            latency of encoding the entry
+//	log_sink_bytes_total{sink}             bytes written to the sink
+//	log_write_duration_seconds{sink}       latency of writing to the sink, including the retries
+//	log_sink_errors_total{sink}            failed writes of the sink
+//	log_dropped_total{reason}              entries dropped, see log.DropQueueFull and so on
+//	log_rotations_total{sink}              rotations of the file sink
+type Collector struct {
+	entries  *prometheus.CounterVec
+	encode   prometheus.Histogram
+	bytes    *prometheus.CounterVec
+	write    *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	dropped  *prometheus.CounterVec
+	rotation *prometheus.CounterVec
+	logger   bool
+}
+
+// NewCollector returns the collector, which should be registered to the prometheus registry.
+func NewCollector(opts ...Option) *Collector {
+	c := config{buckets: DefaultBuckets, loggerLabel: true}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	entryLabels := []string{"level"}
+	if c.loggerLabel {
+		entryLabels = append(entryLabels, "logger")
+	}
+	const subsystem = "log"
+	return &Collector{
+		logger: c.loggerLabel,
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "entries_total",
+			Help:        "Total number of log entries written, by level and logger.",
+			ConstLabels: c.constLabels,
+		}, entryLabels),
+		encode: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "encode_duration_seconds",
+			Help:        "Latency of encoding the log entries.",
+			ConstLabels: c.constLabels,
+			Buckets:     c.buckets,
+		}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "sink_bytes_total",
+			Help:        "Total bytes written to the sink.",
+			ConstLabels: c.constLabels,
+		}, []string{"sink"}),
+		write: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "write_duration_seconds",
+			Help:        "Latency of writing to the sink, including the retries.",
+			ConstLabels: c.constLabels,
+			Buckets:     c.buckets,
+		}, []string{"sink"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "sink_errors_total",
+			Help:        "Total number of failed writes of the sink.",
+			ConstLabels: c.constLabels,
+		}, []string{"sink"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "dropped_total",
+			Help:        "Total number of log entries dropped, by reason.",
+			ConstLabels: c.constLabels,
+		}, []string{"reason"}),
+		rotation: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   subsystem,
+			Name:        "rotations_total",
+			Help:        "Total number of rotations of the file sink.",
+			ConstLabels: c.constLabels,
+		}, []string{"sink"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.entries.Describe(ch)
+	c.encode.Describe(ch)
+	c.bytes.Describe(ch)
+	c.write.Describe(ch)
+	c.errors.Describe(ch)
+	c.dropped.Describe(ch)
+	c.rotation.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.entries.Collect(ch)
+	c.encode.Collect(ch)
+	c.bytes.Collect(ch)
+	c.write.Collect(ch)
+	c.errors.Collect(ch)
+	c.dropped.Collect(ch)
+	c.rotation.Collect(ch)
+}
+
+// Entry implements log.Metrics.
+func (c *Collector) Entry(level zapcore.Level, logger string) {
+	if c.logger {
+		c.entries.WithLabelValues(level.String(), logger).Inc()
+	} else {
+		c.entries.WithLabelValues(level.String()).Inc()
+	}
+}
+
+// Encoded implements log.Metrics.
+func (c *Collector) Encoded(d time.Duration) {
+	c.encode.Observe(d.Seconds())
+}
+
+// Written implements log.Metrics.
+func (c *Collector) Written(sink string, n int, d time.Duration) {
+	c.bytes.WithLabelValues(sink).Add(float64(n))
+	c.write.WithLabelValues(sink).Observe(d.Seconds())
+}
+
+// SinkError implements log.Metrics.
+func (c *Collector) SinkError(sink string) {
+	c.errors.WithLabelValues(sink).Inc()
+}
+
+// Dropped implements log.Metrics.
+func (c *Collector) Dropped(reason string, n int) {
+	c.dropped.WithLabelValues(reason).Add(float64(n))
+}
+
+// Rotated implements log.Metrics.
+func (c *Collector) Rotated(sink string) {
+	c.rotation.WithLabelValues(sink).Inc()
+}
@@ -0,0 +1,122 @@
+package promlog_test
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/promlog"
+)
+
+func Test_Collector(t *testing.T) {
+	c := promlog.NewCollector(
+		promlog.WithNamespace("app"),
+		promlog.WithConstLabels(prometheus.Labels{"service": "test"}),
+	)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+
+	w := &bytes.Buffer{}
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, w),
+		log.WithLevel("info"),
+		log.WithMetrics(c),
+	)
+	l.Infox("hello")
+	l.Named("db").Errorx("failed")
+	c.Dropped(log.DropQueueFull, 3)
+
+	want := `
+# HELP app_log_entries_total Total number of log entries written, by level and logger.
+# TYPE app_log_entries_total counter
+app_log_entries_total{level="error",logger="db",service="test"} 1
+app_log_entries_total{level="info",logger="",service="test"} 1
+# HELP app_log_dropped_total Total number of log entries dropped, by reason.
+# TYPE app_log_dropped_total counter
+app_log_dropped_total{reason="queue_full",service="test"} 3
+# HELP app_log_sink_bytes_total Total bytes written to the sink.
+# TYPE app_log_sink_bytes_total counter
+app_log_sink_bytes_total{service="test",sink="custom"} ` + strconv.Itoa(w.Len()) + `
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"app_log_entries_total", "app_log_dropped_total", "app_log_sink_bytes_total")
+	if err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c, "app_log_encode_duration_seconds"); n != 1 {
+		t.Errorf("want encode duration collected, got: %d", n)
+	}
+}
+
+func Test_Collector_WithoutLoggerLabel(t *testing.T) {
+	c := promlog.NewCollector(promlog.WithLoggerLabel(false))
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, &bytes.Buffer{}),
+		log.WithLevel("info"),
+		log.WithMetrics(c),
+	)
+	l.Named("a").Infox("hello")
+	l.Named("b").Infox("hello")
+
+	want := `
+# HELP log_entries_total Total number of log entries written, by level and logger.
+# TYPE log_entries_total counter
+log_entries_total{level="info"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "log_entries_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_MetricRule(t *testing.T) {
+	failed := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "payment_failed_total"}, []string{"provider"})
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "request_duration_seconds"}, []string{"route"})
+
+	l := log.NewLogger(log.WithAdapter(log.AdapterCustom, &bytes.Buffer{})).
+		WithHooks(log.MetricHook(
+			log.MetricRule{
+				Message: "payment_failed",
+				Labels:  []string{"provider"},
+				Observe: promlog.Counter(failed),
+			},
+			log.MetricRule{
+				Message: "request",
+				Value:   "duration",
+				Labels:  []string{"route"},
+				Observe: promlog.Observer(latency),
+			},
+		))
+	l.Errorx("payment_failed", log.String("provider", "stripe"))
+	l.Errorx("payment_failed", log.String("provider", "stripe"))
+	l.Warnx("request", log.String("route", "/pay"), log.Duration("duration", 250*time.Millisecond))
+
+	if got := testutil.ToFloat64(failed.WithLabelValues("stripe")); got != 2 {
+		t.Errorf("want 2 payment failures, got: %v", got)
+	}
+	if n := testutil.CollectAndCount(latency, "request_duration_seconds"); n != 1 {
+		t.Errorf("want the latency observed, got: %d", n)
+	}
+}
+
+func Test_MetricRule_Invalid(t *testing.T) {
+	failed := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "payment_failed_total"}, []string{"provider"})
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "request_duration_seconds"}, []string{"route"})
+
+	// the labels do not match the vec, and the value is negative.
+	promlog.Counter(failed)(1, []string{"stripe", "extra"})
+	promlog.Counter(failed)(-1, []string{"stripe"})
+	promlog.Observer(latency)(1, nil)
+
+	if n := testutil.CollectAndCount(failed); n != 0 {
+		t.Errorf("want nothing counted, got: %d", n)
+	}
+	if n := testutil.CollectAndCount(latency); n != 0 {
+		t.Errorf("want nothing observed, got: %d", n)
+	}
+}
@@ -0,0 +1,34 @@
+package promlog
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Counter returns the log.MetricRule Observe which adds the value to the counter,
+// the label values of the rule are in the order of the labels of the vec.
+// the value is skipped if the labels do not match the vec or the value is negative.
+//
+//	log.MetricRule{Message: "payment_failed", Labels: []string{"provider"}, Observe: promlog.Counter(vec)}
+func Counter(vec *prometheus.CounterVec) func(value float64, labels []string) {
+	return func(value float64, labels []string) {
+		if value < 0 {
+			return
+		}
+		if c, err := vec.GetMetricWithLabelValues(labels...); err == nil {
+			c.Add(value)
+		}
+	}
+}
+
+// Observer returns the log.MetricRule Observe which observes the value with the histogram or summary,
+// the label values of the rule are in the order of the labels of the vec.
+// the value is skipped if the labels do not match the vec.
+//
+//	log.MetricRule{Message: "request", Value: "duration", Observe: promlog.Observer(vec)}
+func Observer(vec prometheus.ObserverVec) func(value float64, labels []string) {
+	return func(value float64, labels []string) {
+		if o, err := vec.GetMetricWithLabelValues(labels...); err == nil {
+			o.Observe(value)
+		}
+	}
+}
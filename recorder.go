@@ -0,0 +1,300 @@
+package log
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// flight recorder partition defined
+const (
+	RecorderPartitionNone      = ""          // all entries in one ring
+	RecorderPartitionGoroutine = "goroutine" // one ring per goroutine
+)
+
+// FlightRecorderConfig flight recorder 配置
+type FlightRecorderConfig struct {
+	// Size 每个分区保留的最近日志条数, 默认1000
+	Size int `yaml:"size" json:"size"`
+	// MaxBytes 所有分区的内存上限(字节, 估算值), 超出时淘汰最久未使用的分区, 默认4MB
+	MaxBytes int `yaml:"maxBytes" json:"maxBytes"`
+	// Partition 分区方式, 默认空, 不分区
+	// goroutine: 按协程分区
+	// 其它: 按该字段的值分区, 如 traceId,requestId, 可由 Valuer 从 context 中获取, 实现按请求分区
+	Partition string `yaml:"partition" json:"partition"`
+	// DumpLevel 触发转储的等级, error,dpanic,panic,fatal 默认error
+	DumpLevel string `yaml:"dumpLevel" json:"dumpLevel"`
+}
+
+// FlightRecorder keeps the last entries which are below the logger level in
+// the memory rings, and dumps them to the sinks when the entry at or above
+// the DumpLevel is logged, or Dump is called, so the preceding debug context
+// is available when an error happens in production.
+type FlightRecorder struct {
+	mu         sync.Mutex
+	size       int
+	maxBytes   int
+	partition  string
+	dumpLevel  zapcore.Level
+	partitions map[string]*list.Element // value is *recorderRing
+	lru        *list.List               // front is the most recently used
+	bytes      int
+}
+
+// recorderRing the ring of one partition.
+type recorderRing struct {
+	key   string
+	items []recorderItem
+	start int // index of the oldest item
+	bytes int
+}
+
+// recorderItem the entry recorded, which is written by the core when dumped.
+type recorderItem struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []Field
+	size   int
+}
+
+// NewFlightRecorder creates a flight recorder, which is enabled with WithFlightRecorder.
+func NewFlightRecorder(cfg FlightRecorderConfig) *FlightRecorder {
+	if cfg.Size <= 0 {
+		cfg.Size = 1000
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 4 << 20
+	}
+	dumpLevel, err := zapcore.ParseLevel(cfg.DumpLevel)
+	if err != nil || dumpLevel < zapcore.ErrorLevel {
+		dumpLevel = zapcore.ErrorLevel
+	}
+	return &FlightRecorder{
+		size:       cfg.Size,
+		maxBytes:   cfg.MaxBytes,
+		partition:  cfg.Partition,
+		dumpLevel:  dumpLevel,
+		partitions: make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Dump writes all the recorded entries to the sinks, oldest partition first,
+// the recorded entries are cleared.
+func (r *FlightRecorder) Dump() error {
+	r.mu.Lock()
+	var items []recorderItem
+	for e := r.lru.Back(); e != nil; e = e.Prev() {
+		items = e.Value.(*recorderRing).appendTo(items)
+	}
+	r.partitions = make(map[string]*list.Element)
+	r.lru.Init()
+	r.bytes = 0
+	r.mu.Unlock()
+
+	return writeRecorderItems(items)
+}
+
+// dump writes the recorded entries of the partition to the sinks.
+func (r *FlightRecorder) dump(key string) error {
+	r.mu.Lock()
+	e, ok := r.partitions[key]
+	if !ok {
+		r.mu.Unlock()
+		return nil
+	}
+	ring := r.lru.Remove(e).(*recorderRing)
+	delete(r.partitions, key)
+	r.bytes -= ring.bytes
+	items := ring.appendTo(nil)
+	r.mu.Unlock()
+
+	return writeRecorderItems(items)
+}
+
+func writeRecorderItems(items []recorderItem) error {
+	var err error
+	for _, item := range items {
+		err = multierr.Append(err, item.core.Write(item.ent, item.fields))
+	}
+	return err
+}
+
+// record the entry into the ring of the partition, the oldest entry is
+// overwritten when the ring is full, and the least recently used partitions
+// are evicted when the memory exceeds the max bytes.
+func (r *FlightRecorder) record(key string, item recorderItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ring *recorderRing
+	if e, ok := r.partitions[key]; ok {
+		r.lru.MoveToFront(e)
+		ring = e.Value.(*recorderRing)
+	} else {
+		ring = &recorderRing{key: key, items: make([]recorderItem, 0, min(r.size, 64))}
+		r.partitions[key] = r.lru.PushFront(ring)
+	}
+	r.bytes += ring.push(item, r.size)
+	for r.bytes > r.maxBytes {
+		oldest := r.lru.Back().Value.(*recorderRing)
+		if oldest != ring {
+			r.lru.Remove(r.lru.Back())
+			delete(r.partitions, oldest.key)
+			r.bytes -= oldest.bytes
+			continue
+		}
+		if len(ring.items) <= 1 {
+			break
+		}
+		r.bytes -= ring.pop()
+	}
+}
+
+// push the item, returns the delta of bytes.
+func (ring *recorderRing) push(item recorderItem, size int) int {
+	if len(ring.items) < size {
+		ring.items = append(ring.items, item)
+		ring.bytes += item.size
+		return item.size
+	}
+	delta := item.size - ring.items[ring.start].size
+	ring.items[ring.start] = item
+	ring.start = (ring.start + 1) % len(ring.items)
+	ring.bytes += delta
+	return delta
+}
+
+// pop the oldest item, returns the bytes released.
+func (ring *recorderRing) pop() int {
+	size := ring.items[ring.start].size
+	items := ring.appendTo(make([]recorderItem, 0, len(ring.items)))
+	ring.items, ring.start = items[1:], 0
+	ring.bytes -= size
+	return size
+}
+
+// appendTo append the items oldest first.
+func (ring *recorderRing) appendTo(items []recorderItem) []recorderItem {
+	items = append(items, ring.items[ring.start:]...)
+	return append(items, ring.items[:ring.start]...)
+}
+
+// recorderCore records the entries which are not enabled by the core,
+// and dumps the partition before the entry at or above the dump level written.
+type recorderCore struct {
+	core      zapcore.Core
+	recorder  *FlightRecorder
+	partition string // the partition value of With fields
+}
+
+func newRecorderCore(core zapcore.Core, r *FlightRecorder) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &recorderCore{core: core, recorder: r}
+}
+
+// Enabled all levels are enabled for recording.
+func (c *recorderCore) Enabled(zapcore.Level) bool { return true }
+
+func (c *recorderCore) With(fields []Field) zapcore.Core {
+	clone := &recorderCore{
+		core:      c.core.With(fields),
+		recorder:  c.recorder,
+		partition: c.partition,
+	}
+	if v, ok := c.partitionOf(fields); ok {
+		clone.partition = v
+	}
+	return clone
+}
+
+func (c *recorderCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *recorderCore) Write(ent zapcore.Entry, fields []Field) error {
+	if !c.core.Enabled(ent.Level) {
+		c.recorder.record(c.partitionKey(fields), recorderItem{
+			core:   c.core,
+			ent:    ent,
+			fields: append([]Field(nil), fields...),
+			size:   recorderEntrySize(ent, fields),
+		})
+		return nil
+	}
+	var err error
+	if ent.Level >= c.recorder.dumpLevel {
+		err = c.recorder.dump(c.partitionKey(fields))
+	}
+	return multierr.Append(err, c.core.Write(ent, fields))
+}
+
+func (c *recorderCore) Sync() error { return c.core.Sync() }
+
+// recording the core records the entries below the level.
+func (c *recorderCore) recording() bool { return true }
+
+// recordingCore the core records the entries below the level of the logger, such as
+// the flight recorder, which is forwarded by the wrapper cores, see Log.logger.
+type recordingCore interface {
+	recording() bool
+}
+
+// isRecording reports whether the core records the entries below the level.
+func isRecording(core zapcore.Core) bool {
+	rc, ok := core.(recordingCore)
+	return ok && rc.recording()
+}
+
+func (c *recorderCore) partitionKey(fields []Field) string {
+	switch c.recorder.partition {
+	case RecorderPartitionNone:
+		return ""
+	case RecorderPartitionGoroutine:
+		return strconv.FormatUint(goroutineID(), 10)
+	default:
+		if v, ok := c.partitionOf(fields); ok {
+			return v
+		}
+		return c.partition
+	}
+}
+
+// partitionOf returns the value of the partition field.
+func (c *recorderCore) partitionOf(fields []Field) (string, bool) {
+	key := c.recorder.partition
+	if key == RecorderPartitionNone || key == RecorderPartitionGoroutine {
+		return "", false
+	}
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != key {
+			continue
+		}
+		if f.Type == zapcore.StringType {
+			return f.String, true
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		return fmt.Sprint(enc.Fields[key]), true
+	}
+	return "", false
+}
+
+// recorderEntrySize estimate the memory of the entry.
+func recorderEntrySize(ent zapcore.Entry, fields []Field) int {
+	size := 128 + len(ent.Message) + len(ent.Stack) // entry struct and caller
+	for _, f := range fields {
+		size += 64 + len(f.Key) + len(f.String)
+		if b, ok := f.Interface.([]byte); ok {
+			size += len(b)
+		}
+	}
+	return size
+}
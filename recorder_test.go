@@ -0,0 +1,120 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+type requestIdKey struct{}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func Test_FlightRecorder_DumpOnError(t *testing.T) {
+	l, buf := newBufferLogger(
+		log.WithLevel("warn"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithFlightRecorder(log.NewFlightRecorder(log.FlightRecorderConfig{Size: 2})),
+	)
+	l.Debugx("dropped by size")
+	l.Debugx("step 1")
+	l.Infox("step 2")
+	l.Warnx("warn")
+	if got := lines(buf.String()); len(got) != 1 || !strings.Contains(got[0], "msg=warn") {
+		t.Fatalf("want only warn written before error, got: %q", got)
+	}
+
+	l.Errorx("failed")
+	got := lines(buf.String())
+	want := []string{"msg=warn", `msg="step 1"`, `msg="step 2"`, "msg=failed"}
+	if len(got) != len(want) {
+		t.Fatalf("want %d lines, got: %q", len(want), got)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("line %d: want %s, got: %s", i, want[i], got[i])
+		}
+	}
+
+	// the recorded entries are cleared after dumped.
+	buf.Reset()
+	l.Errorx("failed again")
+	if got := lines(buf.String()); len(got) != 1 {
+		t.Errorf("want only the error, got: %q", got)
+	}
+}
+
+func Test_FlightRecorder_Partition(t *testing.T) {
+	rec := log.NewFlightRecorder(log.FlightRecorderConfig{Partition: "requestId"})
+	l, buf := newBufferLogger(
+		log.WithLevel("warn"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithFlightRecorder(rec),
+	)
+	l.SetDefaultValuer(func(ctx context.Context) log.Field {
+		id, _ := ctx.Value(requestIdKey{}).(string)
+		return log.String("requestId", id)
+	})
+	ctxA := context.WithValue(context.Background(), requestIdKey{}, "a")
+	ctxB := context.WithValue(context.Background(), requestIdKey{}, "b")
+	l.DebugxContext(ctxA, "debug a")
+	l.DebugxContext(ctxB, "debug b")
+	l.ErrorxContext(ctxA, "failed a")
+
+	got := buf.String()
+	if !strings.Contains(got, `msg="debug a"`) || strings.Contains(got, `msg="debug b"`) {
+		t.Errorf("want only partition a dumped, got: %s", got)
+	}
+
+	buf.Reset()
+	if err := rec.Dump(); err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(buf.String()); len(got) != 1 || !strings.Contains(got[0], `msg="debug b"`) {
+		t.Errorf("want partition b dumped, got: %q", got)
+	}
+}
+
+func Test_FlightRecorder_MaxBytes(t *testing.T) {
+	rec := log.NewFlightRecorder(log.FlightRecorderConfig{
+		Partition: log.RecorderPartitionGoroutine,
+		MaxBytes:  1024,
+	})
+	l, buf := newBufferLogger(
+		log.WithLevel("warn"),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithFlightRecorder(rec),
+	)
+	for i := 0; i < 100; i++ {
+		l.Debugx("debug", log.Int("i", i))
+	}
+	_ = rec.Dump()
+	got := lines(buf.String())
+	if len(got) == 0 || len(got) >= 100 {
+		t.Fatalf("want entries bounded by max bytes, got: %d", len(got))
+	}
+	if !strings.Contains(got[len(got)-1], "i=99") {
+		t.Errorf("want the latest entries kept, got: %s", got[len(got)-1])
+	}
+}
+
+func Test_FlightRecorder_LevelGate(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.DebugLevel)
+	// only the flight recorder records the entries below the level of the logger.
+	l := log.NewLoggerWith(zap.New(core), log.NewAtomicLevelAt(log.WarnLevel))
+	l.Debugx("debug")
+	l.Infox("info")
+	l.Warnx("warn")
+	if got := lines(buf.String()); len(got) != 1 || !strings.Contains(got[0], `"msg":"warn"`) {
+		t.Errorf("want only warn written, got: %q", got)
+	}
+}
@@ -0,0 +1,351 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/natefinch/lumberjack"
+)
+
+// rotate compress format defined
+const (
+	RotateCompressGzip = "gzip"
+	RotateCompressZstd = "zstd"
+)
+
+// RotateHook the hook invoked with the path of each closed segment after the file rotated,
+// it returns the new path of the segment, such as the compressed file, which is passed to the next hook.
+// the hooks run in a background goroutine, off the logging hot path.
+type RotateHook interface {
+	OnRotate(path string) (string, error)
+}
+
+// RotateHookFunc the function implements RotateHook.
+type RotateHookFunc func(path string) (string, error)
+
+// OnRotate implements RotateHook.
+func (f RotateHookFunc) OnRotate(path string) (string, error) { return f(path) }
+
+// NotifyHook returns the hook which invokes the callback with the path of segment,
+// such as upload to the object storage, the path is passed to the next hook unchanged.
+func NotifyHook(fn func(path string) error) RotateHook {
+	return RotateHookFunc(func(path string) (string, error) {
+		return path, fn(path)
+	})
+}
+
+// CompressHook returns the hook which compresses the segment with gzip or zstd,
+// level is the compression level of the format, 0 use the default level.
+// the segment is removed after compressed, and the compressed path is returned.
+func CompressHook(format string, level int) RotateHook {
+	return RotateHookFunc(func(path string) (string, error) {
+		return compressFile(path, format, level)
+	})
+}
+
+// ArchiveHook returns the hook which moves the segment into the archive directory.
+func ArchiveHook(dir string) RotateHook {
+	return RotateHookFunc(func(path string) (string, error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return path, err
+		}
+		dst := filepath.Join(dir, filepath.Base(path))
+		if err := moveFile(path, dst); err != nil {
+			return path, err
+		}
+		return dst, nil
+	})
+}
+
+// toRotateHooks returns the hooks of the config, the RotateCompress and ArchiveDir run first.
+func toRotateHooks(c *Config) []RotateHook {
+	var hooks []RotateHook
+	if c.RotateCompress != "" {
+		hooks = append(hooks, CompressHook(c.RotateCompress, c.RotateCompressLevel))
+	}
+	if c.ArchiveDir != "" {
+		hooks = append(hooks, ArchiveHook(c.ArchiveDir))
+	}
+	return append(hooks, c.RotateHooks...)
+}
+
+// rotateWriter detects the rotation of lumberjack, counts the rotation, and notifies
+// the worker which runs the hooks with the closed segments, the worker is started only
+// if any hooks configured.
+// lumberjack rotates when the size exceeds the max size,
+// which is mirrored here by counting the bytes written.
+type rotateWriter struct {
+	*lumberjack.Logger
+	mu        sync.Mutex
+	size      int64
+	max       int64
+	opened    bool
+	hooks     []RotateHook
+	onError   SinkErrorHandler
+	metrics   Metrics
+	notify    chan struct{}
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	done      map[string]struct{} // the segments have been processed or existed before
+}
+
+func newRotateWriter(lj *lumberjack.Logger, hooks []RotateHook, onError SinkErrorHandler, m Metrics) *rotateWriter {
+	max := int64(lj.MaxSize) << 20
+	if max == 0 {
+		max = 100 << 20 // same as lumberjack
+	}
+	w := &rotateWriter{
+		Logger:  lj,
+		max:     max,
+		hooks:   hooks,
+		onError: onError,
+		metrics: m,
+	}
+	if len(hooks) > 0 {
+		w.notify = make(chan struct{}, 1)
+		w.stop = make(chan struct{})
+		w.stopped = make(chan struct{})
+		w.done = make(map[string]struct{})
+		for _, seg := range w.segments() {
+			w.done[seg] = struct{}{}
+		}
+		go w.run()
+	}
+	return w
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rotated := false
+	if !w.opened {
+		w.opened = true
+		if fi, err := os.Stat(w.filename()); err == nil {
+			w.size = fi.Size()
+			rotated = w.size+int64(len(p)) >= w.max
+		}
+	} else {
+		rotated = w.size+int64(len(p)) > w.max
+	}
+	if rotated {
+		w.size = 0
+	}
+	n, err := w.Logger.Write(p)
+	w.size += int64(n)
+	if rotated {
+		w.metrics.Rotated(SinkFile)
+		w.wakeup()
+	}
+	return n, err
+}
+
+// Rotate rotates the file and runs the hooks.
+func (w *rotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.Logger.Rotate()
+	w.opened, w.size = true, 0
+	if err == nil {
+		w.metrics.Rotated(SinkFile)
+	}
+	w.wakeup()
+	return err
+}
+
+// Close stops the worker and closes the file.
+func (w *rotateWriter) Close() error {
+	if w.stop != nil {
+		w.closeOnce.Do(func() { close(w.stop) })
+		<-w.stopped
+	}
+	return w.Logger.Close()
+}
+
+func (w *rotateWriter) wakeup() {
+	if w.notify == nil {
+		return
+	}
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *rotateWriter) run() {
+	defer close(w.stopped)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.notify:
+		}
+		segs := w.segments()
+		// only the segments still existed are kept, such as failed by the hook.
+		done := make(map[string]struct{}, len(segs))
+		for _, seg := range segs {
+			done[seg] = struct{}{}
+			if _, ok := w.done[seg]; ok {
+				continue
+			}
+			path := seg
+			for _, hook := range w.hooks {
+				var err error
+				if path, err = hook.OnRotate(path); err != nil {
+					if w.onError != nil {
+						w.onError(SinkFile, fmt.Errorf("log: rotate hook %s: %w", seg, err))
+					}
+					break
+				}
+			}
+		}
+		w.done = done
+		w.retain()
+	}
+}
+
+func (w *rotateWriter) filename() string {
+	if w.Filename != "" {
+		return w.Filename
+	}
+	return filepath.Join(os.TempDir(), filepath.Base(os.Args[0])+"-lumberjack.log")
+}
+
+// segments returns the uncompressed backups, oldest first, which is named <name>-<timestamp><ext>.
+func (w *rotateWriter) segments() []string {
+	filename := w.filename()
+	dir := filepath.Dir(filename)
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var segs []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) || len(name) < len(prefix)+len(ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, name[len(prefix):len(name)-len(ext)]); err == nil {
+			segs = append(segs, filepath.Join(dir, name))
+		}
+	}
+	// the timestamp is sortable
+	sort.Strings(segs)
+	return segs
+}
+
+// retain applies MaxBackups and MaxAge to the backups compressed with zstd, which are
+// not recognized by lumberjack, the backups moved to ArchiveDir are not retained.
+func (w *rotateWriter) retain() {
+	if w.MaxBackups <= 0 && w.MaxAge <= 0 {
+		return
+	}
+	backups, _ := listBackups(w.filename())
+	cutoff := time.Now().Add(-time.Duration(w.MaxAge) * 24 * time.Hour)
+	for i, b := range backups {
+		if !strings.HasSuffix(b.path, ".zst") {
+			continue
+		}
+		newer := len(backups) - 1 - i
+		if (w.MaxBackups > 0 && newer >= w.MaxBackups) || (w.MaxAge > 0 && b.time.Before(cutoff)) {
+			_ = os.Remove(b.path)
+		}
+	}
+}
+
+func compressFile(path, format string, level int) (string, error) {
+	var (
+		dst    string
+		newEnc func(io.Writer) (io.WriteCloser, error)
+	)
+	switch strings.ToLower(format) {
+	case RotateCompressGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		dst = path + ".gz"
+		newEnc = func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, level) }
+	case RotateCompressZstd:
+		opts := []zstd.EOption{}
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		dst = path + ".zst"
+		newEnc = func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w, opts...) }
+	default:
+		return path, fmt.Errorf("log: unsupported compress format %q", format)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return path, err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return path, err
+	}
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return path, err
+	}
+	enc, err := newEnc(f)
+	if err == nil {
+		if _, err = io.Copy(enc, src); err == nil {
+			err = enc.Close()
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		_ = os.Remove(dst)
+		return path, err
+	}
+	_ = src.Close()
+	return dst, os.Remove(path)
+}
+
+// moveFile rename the file, or copy and remove it across the devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if err = errors.Join(err, out.Close()); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	_ = in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,126 @@
+package log_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/things-go/log"
+)
+
+func rotateLogger(t *testing.T, opts ...log.Option) (*log.Log, chan string) {
+	rotated := make(chan string, 1)
+	opts = append([]log.Option{
+		log.WithFormat(log.FormatLogfmt),
+		log.WithAdapter(log.AdapterFile),
+		log.WithPath(t.TempDir()),
+		log.WithFilename("app.log"),
+		log.WithMaxSize(1),
+	}, opts...)
+	opts = append(opts, log.WithRotateHooks(log.NotifyHook(func(path string) error {
+		rotated <- path
+		return nil
+	})))
+	return log.NewLogger(opts...), rotated
+}
+
+func waitRotated(t *testing.T, rotated chan string) string {
+	select {
+	case path := <-rotated:
+		return path
+	case <-time.After(5 * time.Second):
+		t.Fatal("rotate hook not invoked")
+		return ""
+	}
+}
+
+func Test_Rotate_ZstdArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive")
+	l, rotated := rotateLogger(t,
+		log.WithRotateCompress(log.RotateCompressZstd, 3),
+		log.WithArchiveDir(archive),
+	)
+	payload := strings.Repeat("x", 600<<10)
+	l.Warnx("first", log.String("payload", payload))
+	l.Warnx("second", log.String("payload", payload))
+
+	path := waitRotated(t, rotated)
+	if filepath.Dir(path) != archive || !strings.HasSuffix(path, ".log.zst") {
+		t.Fatalf("want compressed segment in archive, got: %s", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dec, err := zstd.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dec.Close()
+	b, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, "msg=first") || strings.Contains(got, "msg=second") {
+		t.Errorf("want the first entry in the closed segment, got size: %d", len(got))
+	}
+}
+
+func Test_Rotate_Gzip(t *testing.T) {
+	l, rotated := rotateLogger(t, log.WithRotateCompress(log.RotateCompressGzip, gzip.BestSpeed))
+	payload := strings.Repeat("x", 600<<10)
+	l.Warnx("first", log.String("payload", payload))
+	l.Warnx("second", log.String("payload", payload))
+
+	path := waitRotated(t, rotated)
+	if !strings.HasSuffix(path, ".log.gz") {
+		t.Fatalf("want gzip segment, got: %s", path)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(path, ".gz")); !os.IsNotExist(err) {
+		t.Errorf("want the uncompressed segment removed, got: %v", err)
+	}
+}
+
+func Test_Rotate_ZstdRetention(t *testing.T) {
+	dir := t.TempDir()
+	old := []string{
+		"app-2024-01-01T00-00-00.000.log.zst",
+		"app-2024-01-02T00-00-00.000.log.zst",
+	}
+	for _, name := range append(old, "app-worker.log.zst") {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l, rotated := rotateLogger(t,
+		log.WithPath(dir),
+		log.WithMaxBackups(1),
+		log.WithRotateCompress(log.RotateCompressZstd, 0),
+	)
+	defer l.Close()
+	payload := strings.Repeat("x", 600<<10)
+	l.Warnx("first", log.String("payload", payload))
+	l.Warnx("second", log.String("payload", payload))
+	waitRotated(t, rotated)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(filepath.Join(dir, old[1])); os.IsNotExist(err) {
+			break
+		}
+	}
+	for _, name := range old {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s: want the old zstd backup removed, got: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app-worker.log.zst")); err != nil {
+		t.Errorf("want other files kept, got: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// sink name defined, which is passed to the SinkErrorHandler.
+const (
+	SinkConsole  = "console"
+	SinkFile     = "file"
+	SinkCustom   = "custom" // custom[<index>] if more than one custom writer
+	SinkSyslog   = "syslog"
+	SinkJournald = "journald"
+	SinkNet      = "net"
+	SinkStderr   = "stderr" // fallback
+	SinkStdout   = "stdout" // fallback
+)
+
+// SinkErrorHandler the handler invoked with the failing sink and error,
+// such as the file sink when the disk is full.
+type SinkErrorHandler func(sink string, err error)
+
+// sinkWriter wrap the sink with retry for transient failures, and write
+// to the fallback chain in order when the sink failed.
+type sinkWriter struct {
+	name      string
+	w         zapcore.WriteSyncer
+	retries   int
+	backoff   time.Duration
+	fallbacks []*sinkWriter
+	onError   SinkErrorHandler
+	metrics   Metrics
+}
+
+// newSinkWriter wrap the sink with the Fallback, WriteRetries, ErrorHandler and Metrics of the config,
+// the sink is returned directly if none of them configured.
+func newSinkWriter(name string, w zapcore.WriteSyncer, c *Config) zapcore.WriteSyncer {
+	if len(c.Fallback) == 0 && c.WriteRetries <= 0 && c.ErrorHandler == nil && c.Metrics == nil {
+		return w
+	}
+	backoff := c.RetryBackoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+	sw := &sinkWriter{
+		name:    name,
+		w:       w,
+		retries: c.WriteRetries,
+		backoff: backoff,
+		onError: c.ErrorHandler,
+		metrics: toMetrics(c),
+	}
+	for _, fb := range c.Fallback {
+		sw.fallbacks = append(sw.fallbacks, &sinkWriter{name: fb, w: toFallbackWriter(fb)})
+	}
+	return sw
+}
+
+// newSinkRescue returns the rescue of the async adapters, such as loki, elasticsearch and forward,
+// which reports the error of the batch failed and writes the entries to the Fallback,
+// nil if neither Fallback nor ErrorHandler configured. the error of the sink is counted by the adapter.
+func newSinkRescue(name string, c *Config) func(p []byte, err error) error {
+	if len(c.Fallback) == 0 && c.ErrorHandler == nil {
+		return nil
+	}
+	sw := &sinkWriter{
+		name:    name,
+		onError: c.ErrorHandler,
+		metrics: nopMetrics{},
+	}
+	for _, fb := range c.Fallback {
+		sw.fallbacks = append(sw.fallbacks, &sinkWriter{name: fb, w: toFallbackWriter(fb)})
+	}
+	return sw.rescue
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := w.write(p)
+	if err == nil {
+		w.metrics.Written(w.name, len(p), time.Since(start))
+		return len(p), nil
+	}
+	// the fallbacks write the tail which is not written by the sink.
+	if err = w.rescue(p[n:], err); err != nil {
+		w.metrics.Dropped(DropSinkFailed, 1)
+		return n, err
+	}
+	return len(p), nil
+}
+
+// rescue reports the error of the sink, and writes p to the fallbacks in order,
+// it returns the error of the sink if all the fallbacks failed.
+func (w *sinkWriter) rescue(p []byte, err error) error {
+	w.reportError(w.name, err)
+	for _, fb := range w.fallbacks {
+		start := time.Now()
+		_, fbErr := fb.write(p)
+		if fbErr == nil {
+			w.metrics.Written(fb.name, len(p), time.Since(start))
+			return nil
+		}
+		w.reportError(fb.name, fbErr)
+	}
+	return err
+}
+
+// write with retry if the error is transient, the rest is retried when written partially,
+// it returns the number of bytes written.
+func (w *sinkWriter) write(p []byte) (int, error) {
+	backoff := w.backoff
+	written := 0
+	for i := 0; ; i++ {
+		n, err := w.w.Write(p)
+		written += n
+		if err == nil {
+			return written, nil
+		}
+		if i >= w.retries || !isTransientError(err) {
+			return written, err
+		}
+		p = p[n:]
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *sinkWriter) reportError(name string, err error) {
+	w.metrics.SinkError(name)
+	if w.onError != nil {
+		w.onError(name, err)
+	}
+}
+
+func (w *sinkWriter) Sync() error {
+	err := w.w.Sync()
+	for _, fb := range w.fallbacks {
+		_ = fb.w.Sync()
+	}
+	return err
+}
+
+// isTransientError reports whether the error may be resolved by retry.
+func isTransientError(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, os.ErrDeadlineExceeded) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, syscall.ENOBUFS)
+}
+
+// toFallbackWriter returns the fallback writer, stderr, stdout or the file path.
+func toFallbackWriter(name string) zapcore.WriteSyncer {
+	switch name {
+	case SinkStderr:
+		return zapcore.AddSync(os.Stderr)
+	case SinkStdout:
+		return zapcore.AddSync(os.Stdout)
+	default:
+		return &fallbackFile{path: name}
+	}
+}
+
+// fallbackFile the file is opened on the first write, so it is not created if never used.
+type fallbackFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func (f *fallbackFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	return f.file.Write(p)
+}
+
+func (f *fallbackFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// customSinkName returns the name of the i-th custom writer.
+func customSinkName(i, n int) string {
+	if n == 1 {
+		return SinkCustom
+	}
+	return SinkCustom + "[" + strconv.Itoa(i) + "]"
+}
@@ -0,0 +1,124 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+// failWriter fails the first n writes with err, n < 0 always fails.
+type failWriter struct {
+	bytes.Buffer
+	n   int
+	err error
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n != 0 {
+		w.n--
+		return 0, w.err
+	}
+	return w.Buffer.Write(p)
+}
+
+func Test_Sink_Fallback(t *testing.T) {
+	fallback := filepath.Join(t.TempDir(), "fallback.log")
+	var (
+		mu     sync.Mutex
+		failed []string
+	)
+	w := &failWriter{n: -1, err: syscall.ENOSPC}
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, w),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithFallback("/nonexistent/dir/fallback.log", fallback),
+		log.WithErrorHandler(func(sink string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, sink)
+		}),
+	)
+	l.Warnx("disk full")
+
+	b, err := os.ReadFile(fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "msg=\"disk full\"") {
+		t.Errorf("want entry in fallback, got: %s", b)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failed) != 2 || failed[0] != log.SinkCustom || failed[1] != "/nonexistent/dir/fallback.log" {
+		t.Errorf("want custom and the first fallback failed, got: %v", failed)
+	}
+}
+
+func Test_Sink_Retry(t *testing.T) {
+	var called bool
+	w := &failWriter{n: 2, err: syscall.EAGAIN}
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, w),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithWriteRetry(2, time.Millisecond),
+		log.WithErrorHandler(func(string, error) { called = true }),
+	)
+	l.Warnx("hello")
+	if called || !strings.Contains(w.String(), "msg=hello") {
+		t.Errorf("want written after retry, got: %q, handler called: %v", w.String(), called)
+	}
+
+	// not transient, no retry.
+	w = &failWriter{n: 1, err: errors.New("permission denied")}
+	var sinkErr error
+	l = log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, w),
+		log.WithWriteRetry(2, time.Millisecond),
+		log.WithErrorHandler(func(_ string, err error) { sinkErr = err }),
+	)
+	l.Warnx("hello")
+	if sinkErr == nil || w.Len() != 0 {
+		t.Errorf("want error without retry, got: %v, %q", sinkErr, w.String())
+	}
+}
+
+// partialWriter writes the first n bytes, then fails.
+type partialWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *partialWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.n {
+		return w.Buffer.Write(p)
+	}
+	n, _ := w.Buffer.Write(p[:w.n])
+	return n, errors.New("permission denied")
+}
+
+func Test_Sink_FallbackTail(t *testing.T) {
+	fallback := filepath.Join(t.TempDir(), "fallback.log")
+	w := &partialWriter{n: 10}
+	l := log.NewLogger(
+		log.WithAdapter(log.AdapterCustom, w),
+		log.WithFormat(log.FormatLogfmt),
+		log.WithFallback(fallback),
+	)
+	l.Warnx("partial")
+
+	b, err := os.ReadFile(fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.String() + string(b); !strings.HasSuffix(got, "msg=partial\n") || len(b) != len(got)-10 {
+		t.Errorf("want the fallback writes the tail, got: %q, %q", w.String(), b)
+	}
+}
@@ -0,0 +1,313 @@
+// Package sqllog provides the database/sql/driver wrapper which logs the queries through *log.Log.
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/things-go/log"
+)
+
+// Option the option of the wrapper.
+type Option func(c *config)
+
+type config struct {
+	log           *log.Log
+	slowThreshold time.Duration
+	withoutArgs   bool
+	redactArgs    func(query string, args []driver.NamedValue) []any
+}
+
+// WithSlowThreshold the query slower than the threshold is logged in warn level, default 0, disable.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(c *config) { c.slowThreshold = threshold }
+}
+
+// WithoutArgs the args of the query are not logged.
+func WithoutArgs() Option {
+	return func(c *config) { c.withoutArgs = true }
+}
+
+// WithArgsRedactor the redactor which returns the args logged, such as masking the password.
+func WithArgsRedactor(f func(query string, args []driver.NamedValue) []any) Option {
+	return func(c *config) { c.redactArgs = f }
+}
+
+func newConfig(l *log.Log, opts []Option) *config {
+	c := &config{log: l}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Open opens the database with the registered driver, and the queries are logged,
+// the entries are logged with the context of the query, so the Valuers see the trace id of the caller.
+//
+//	db, err := sqllog.Open("mysql", dsn, l.Named("sql"), sqllog.WithSlowThreshold(time.Second))
+func Open(driverName, dsn string, l *log.Log, opts ...Option) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(WrapConnector(connector, l, opts...)), nil
+	}
+	return sql.OpenDB(&dsnConnector{dsn: dsn, driver: WrapDriver(d, l, opts...)}), nil
+}
+
+// WrapConnector returns the connector which logs the queries.
+func WrapConnector(connector driver.Connector, l *log.Log, opts ...Option) driver.Connector {
+	c := newConfig(l, opts)
+	return &wrappedConnector{
+		Connector: connector,
+		driver:    &wrappedDriver{Driver: connector.Driver(), config: c},
+		config:    c,
+	}
+}
+
+// WrapDriver returns the driver which logs the queries.
+func WrapDriver(d driver.Driver, l *log.Log, opts ...Option) driver.Driver {
+	return &wrappedDriver{Driver: d, config: newConfig(l, opts)}
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c *dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+type wrappedConnector struct {
+	driver.Connector
+	driver *wrappedDriver
+	*config
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: conn, config: c.config}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver { return c.driver }
+
+type wrappedDriver struct {
+	driver.Driver
+	*config
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: conn, config: d.config}, nil
+}
+
+// logQuery logs the query, the failed query in error level,
+// the slow query in warn level, others in info level.
+func (c *config) logQuery(ctx context.Context, msg, query string, args []driver.NamedValue, start time.Time, result driver.Result, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	latency := time.Since(start)
+	fields := make([]log.Field, 0, 5)
+	fields = append(fields, log.String("query", query), log.Duration("latency", latency))
+	if len(args) > 0 && !c.withoutArgs {
+		fields = append(fields, log.Any("args", c.args(query, args)))
+	}
+	if result != nil {
+		if rows, e := result.RowsAffected(); e == nil {
+			fields = append(fields, log.Int64("rows", rows))
+		}
+	}
+	if err != nil {
+		fields = append(fields, log.Err(err))
+	}
+	switch {
+	case err != nil:
+		c.log.ErrorxContext(ctx, msg, fields...)
+	case c.slowThreshold > 0 && latency > c.slowThreshold:
+		c.log.WarnxContext(ctx, msg, append(fields, log.Duration("slowThreshold", c.slowThreshold))...)
+	default:
+		c.log.InfoxContext(ctx, msg, fields...)
+	}
+}
+
+func (c *config) args(query string, args []driver.NamedValue) []any {
+	if c.redactArgs != nil {
+		return c.redactArgs(query, args)
+	}
+	vs := make([]any, len(args))
+	for i, arg := range args {
+		vs[i] = arg.Value
+	}
+	return vs
+}
+
+type wrappedConn struct {
+	driver.Conn
+	*config
+}
+
+func (c *wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		c.logQuery(ctx, "sql prepare", query, nil, time.Now(), nil, err)
+		return nil, err
+	}
+	return &wrappedStmt{Stmt: stmt, query: query, config: c.config}, nil
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+	// the same as database/sql, the options can not be applied by Begin.
+	if opts.ReadOnly {
+		return nil, errors.New("sqllog: driver does not support read-only transactions")
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sqllog: driver does not support non-default isolation level")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Conn.Begin() //nolint:staticcheck
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := ec.ExecContext(ctx, query, args)
+	c.logQuery(ctx, "sql exec", query, args, start, result, err)
+	return result, err
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.logQuery(ctx, "sql query", query, args, start, nil, err)
+	return rows, err
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if rs, ok := c.Conn.(driver.SessionResetter); ok {
+		return rs.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type wrappedStmt struct {
+	driver.Stmt
+	query string
+	*config
+}
+
+func (s *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		result driver.Result
+		err    error
+	)
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else {
+		var vs []driver.Value
+		if vs, err = namedValues(args); err == nil {
+			result, err = s.Stmt.Exec(vs) //nolint:staticcheck
+		}
+	}
+	s.logQuery(ctx, "sql exec", s.query, args, start, result, err)
+	return result, err
+}
+
+func (s *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		var vs []driver.Value
+		if vs, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(vs) //nolint:staticcheck
+		}
+	}
+	s.logQuery(ctx, "sql query", s.query, args, start, nil, err)
+	return rows, err
+}
+
+func (s *wrappedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqllog: driver does not support the use of Named Parameters")
+		}
+		vs[i] = arg.Value
+	}
+	return vs, nil
+}
@@ -0,0 +1,178 @@
+package sqllog_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/things-go/log"
+	"github.com/things-go/log/sqllog"
+)
+
+// fakeDriver returns the rows affected as the first arg, and fails the query "fail".
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "fail" {
+		return nil, errors.New("syntax error")
+	}
+	if query == "slow" {
+		time.Sleep(20 * time.Millisecond)
+	}
+	var rows int64
+	if len(args) > 0 {
+		rows, _ = args[0].Value.(int64)
+	}
+	return driver.RowsAffected(rows), nil
+}
+
+type fakeStmt struct{ query string }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("sqllog-fake", fakeDriver{})
+}
+
+type traceIdKey struct{}
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+		log.WithFormat(log.FormatLogfmt),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func openDB(t *testing.T, opts ...sqllog.Option) (*sql.DB, *bytes.Buffer) {
+	t.Helper()
+	l, buf := newBufferLogger()
+	l.SetDefaultValuer(log.TraceId(func(ctx context.Context) string {
+		id, _ := ctx.Value(traceIdKey{}).(string)
+		return id
+	}))
+	db, err := sqllog.Open("sqllog-fake", "", l, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, buf
+}
+
+func assertContains(t *testing.T, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s, got: %s", want, got)
+		}
+	}
+}
+
+func Test_Exec(t *testing.T) {
+	db, buf := openDB(t)
+	ctx := context.WithValue(context.Background(), traceIdKey{}, "trace-1")
+	if _, err := db.ExecContext(ctx, "update users set name = ?", int64(3)); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, buf.String(),
+		"level=info", `msg="sql exec"`, `query="update users set name = ?"`,
+		"args=[3]", "rows=3", "latency=", "traceId=trace-1",
+	)
+
+	buf.Reset()
+	if _, err := db.ExecContext(ctx, "fail"); err == nil {
+		t.Fatal("want error")
+	}
+	assertContains(t, buf.String(), "level=error", `error="syntax error"`, "traceId=trace-1")
+}
+
+func Test_Stmt(t *testing.T) {
+	db, buf := openDB(t)
+	rows, err := db.QueryContext(context.Background(), "select id from users where id = ?", int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+	// the fake conn does not implement QueryerContext, the query is prepared.
+	assertContains(t, buf.String(), `msg="sql query"`, `query="select id from users where id = ?"`, "args=[1]")
+}
+
+func Test_RedactAndSlow(t *testing.T) {
+	db, buf := openDB(t,
+		sqllog.WithSlowThreshold(10*time.Millisecond),
+		sqllog.WithArgsRedactor(func(query string, args []driver.NamedValue) []any {
+			vs := make([]any, len(args))
+			for i := range args {
+				vs[i] = "***"
+			}
+			return vs
+		}),
+	)
+	if _, err := db.Exec("slow", int64(1), "password"); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	assertContains(t, got, "level=warn", "slowThreshold=10ms", `args="[\"***\",\"***\"]"`)
+	if strings.Contains(got, "password") {
+		t.Errorf("want args redacted, got: %s", got)
+	}
+
+	db, buf = openDB(t, sqllog.WithoutArgs())
+	if _, err := db.Exec("update users set name = ?", int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "args=") {
+		t.Errorf("want args omitted, got: %s", buf.String())
+	}
+}
+
+func Test_BeginTx(t *testing.T) {
+	db, _ := openDB(t)
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = tx.Rollback()
+
+	// the fake conn does not implement ConnBeginTx.
+	for _, opts := range []*sql.TxOptions{
+		{ReadOnly: true},
+		{Isolation: sql.LevelSerializable},
+	} {
+		if _, err = db.BeginTx(context.Background(), opts); err == nil {
+			t.Errorf("want error for %+v", opts)
+		}
+	}
+}
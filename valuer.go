@@ -6,11 +6,55 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// ValuerErrorKey the key of the field which report the panic of the Valuer.
+const ValuerErrorKey = "valuer_error"
+
 // Valuer is returns a log value.
+// Valuer is evaluated lazily, only for the entry that will be written actually.
+// Valuer can return Skip() to emit nothing.
 type Valuer func(ctx context.Context) Field
 
+// callValuer call the Valuer, if the Valuer panics, recover it and
+// report with a ValuerErrorKey field.
+func callValuer(ctx context.Context, f Valuer) (field Field) {
+	defer func() {
+		if r := recover(); r != nil {
+			field = zap.String(ValuerErrorKey, fmt.Sprint(r))
+		}
+	}()
+	return f(ctx)
+}
+
+// OmitEmpty returns a Valuer that return Skip() if the Valuer returns
+// an empty value, such as empty string, empty bytes or nil value.
+func OmitEmpty(v Valuer) Valuer {
+	return func(ctx context.Context) Field {
+		field := v(ctx)
+		if isEmptyField(field) {
+			return zap.Skip()
+		}
+		return field
+	}
+}
+
+func isEmptyField(f Field) bool {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String == ""
+	case zapcore.BinaryType, zapcore.ByteStringType:
+		b, _ := f.Interface.([]byte)
+		return len(b) == 0
+	case zapcore.ReflectType, zapcore.StringerType, zapcore.ErrorType,
+		zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType, zapcore.ArrayMarshalerType:
+		return f.Interface == nil
+	default:
+		return false
+	}
+}
+
 /**************************** immutable Valuer ******************************************/
 
 func wrapperField(field Field) Valuer {
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type levelCtxKey struct{}
+
+// levelContext carry the level of the entry which is being logged.
+type levelContext struct {
+	context.Context
+	level Level
+}
+
+// newLevelContext returns the context which carry the entry level, which is allocated
+// for each entry, so the Valuer may hold it, such as in a goroutine.
+func newLevelContext(ctx context.Context, level Level) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &levelContext{Context: ctx, level: level}
+}
+
+func (c *levelContext) Value(key any) any {
+	if key == (levelCtxKey{}) {
+		return c.level
+	}
+	return c.Context.Value(key)
+}
+
+// LevelFromContext returns the level of the entry which is being logged.
+// It is only available in the context passed to Valuer.
+func LevelFromContext(ctx context.Context) (Level, bool) {
+	if c, ok := ctx.(*levelContext); ok {
+		return c.level, true
+	}
+	lvl, ok := ctx.Value(levelCtxKey{}).(Level)
+	return lvl, ok
+}
+
+// OnLevel returns a Valuer which is evaluated only when the entry level
+// is at or above lvl, otherwise returns Skip().
+func OnLevel(lvl Level, v Valuer) Valuer {
+	return func(ctx context.Context) Field {
+		if l, ok := LevelFromContext(ctx); ok && l < lvl {
+			return zap.Skip()
+		}
+		return v(ctx)
+	}
+}
+
+// When returns a Valuer which is evaluated only when pred returns true,
+// otherwise returns Skip().
+// the entry level can be get by LevelFromContext in pred.
+func When(pred func(ctx context.Context) bool, v Valuer) Valuer {
+	return func(ctx context.Context) Field {
+		if !pred(ctx) {
+			return zap.Skip()
+		}
+		return v(ctx)
+	}
+}
+
+// Group returns a Valuer which groups the fields of Valuers as a Dict with key.
+// the skipped fields are dropped, if all of them are skipped, returns Skip().
+func Group(key string, vs ...Valuer) Valuer {
+	return func(ctx context.Context) Field {
+		var fields []Field
+		for _, v := range vs {
+			field := callValuer(ctx, v)
+			if field.Type == zapcore.SkipType {
+				continue
+			}
+			if fields == nil {
+				fields = make([]Field, 0, len(vs))
+			}
+			fields = append(fields, field)
+		}
+		if len(fields) == 0 {
+			return zap.Skip()
+		}
+		return zap.Dict(key, fields...)
+	}
+}
@@ -0,0 +1,269 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldFunc constructs a Field with the key and the value.
+type FieldFunc[T any] func(key string, v T) Field
+
+var (
+	encoderMu       sync.RWMutex
+	encoderRegistry = map[reflect.Type]any{}
+)
+
+var (
+	objectMarshalerType = reflect.TypeOf((*zapcore.ObjectMarshaler)(nil)).Elem()
+	arrayMarshalerType  = reflect.TypeOf((*zapcore.ArrayMarshaler)(nil)).Elem()
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// RegisterEncoder registers the Field constructor of type T,
+// which is used by From, Slice and Map instead of the builtin one.
+// It should be called before constructing the Valuer, such as in init.
+func RegisterEncoder[T any](fn FieldFunc[T]) {
+	encoderMu.Lock()
+	defer encoderMu.Unlock()
+	encoderRegistry[typeOf[T]()] = fn
+}
+
+// From returns a Valuer with the value of vf, the Field constructor of T
+// is picked once at construction, so builtin types and the types
+// with underlying builtin type are zero-allocation.
+func From[T any](key string, vf func(context.Context) T) Valuer {
+	fn := FieldFuncOf[T]()
+	return func(ctx context.Context) Field {
+		return fn(key, vf(ctx))
+	}
+}
+
+// Slice returns a Valuer with the slice of vf, which is encoded as an array,
+// the Field constructor of T is picked once at construction.
+func Slice[T any](key string, vf func(context.Context) []T) Valuer {
+	fn := FieldFuncOf[T]()
+	return func(ctx context.Context) Field {
+		return zap.Array(key, sliceMarshaler[T]{vs: vf(ctx), fn: fn})
+	}
+}
+
+// Map returns a Valuer with the map of vf, which is encoded as an object with sorted keys,
+// the Field constructor of V is picked once at construction.
+func Map[K comparable, V any](key string, vf func(context.Context) map[K]V) Valuer {
+	keyFn := keyStringFuncOf[K]()
+	fn := FieldFuncOf[V]()
+	return func(ctx context.Context) Field {
+		return zap.Object(key, mapMarshaler[K, V]{m: vf(ctx), keyFn: keyFn, fn: fn})
+	}
+}
+
+// FieldFuncOf returns the Field constructor of T, the order is:
+//   - the registered one by RegisterEncoder
+//   - time.Time, time.Duration and []byte
+//   - zapcore.ObjectMarshaler, zapcore.ArrayMarshaler, error and fmt.Stringer, the nil pointer is null
+//   - the builtin types and the types with underlying builtin type
+//   - otherwise zap.Any
+func FieldFuncOf[T any]() FieldFunc[T] {
+	typ := typeOf[T]()
+
+	encoderMu.RLock()
+	registered, ok := encoderRegistry[typ]
+	encoderMu.RUnlock()
+	if ok {
+		return registered.(FieldFunc[T])
+	}
+
+	switch typ {
+	case reflect.TypeOf(time.Time{}):
+		return func(key string, v T) Field { return zap.Time(key, as[time.Time](v)) }
+	case reflect.TypeOf(time.Duration(0)):
+		return func(key string, v T) Field { return zap.Duration(key, as[time.Duration](v)) }
+	case reflect.TypeOf([]byte(nil)):
+		return func(key string, v T) Field { return zap.Binary(key, as[[]byte](v)) }
+	}
+	if typ.Kind() == reflect.Interface {
+		return func(key string, v T) Field { return zap.Any(key, v) }
+	}
+	// the marshaler methods of the typed nil pointer may panic inside the encoder,
+	// it is encoded as null instead, zap recovers the error and fmt.Stringer itself.
+	isPointer := typ.Kind() == reflect.Pointer
+	switch {
+	case typ.Implements(objectMarshalerType):
+		return func(key string, v T) Field {
+			if isPointer && as[unsafe.Pointer](v) == nil {
+				return zap.Reflect(key, nil)
+			}
+			return zap.Object(key, any(v).(zapcore.ObjectMarshaler))
+		}
+	case typ.Implements(arrayMarshalerType):
+		return func(key string, v T) Field {
+			if isPointer && as[unsafe.Pointer](v) == nil {
+				return zap.Reflect(key, nil)
+			}
+			return zap.Array(key, any(v).(zapcore.ArrayMarshaler))
+		}
+	case typ.Implements(errorType):
+		return func(key string, v T) Field { return zap.NamedError(key, any(v).(error)) }
+	case typ.Implements(stringerType):
+		return func(key string, v T) Field { return zap.Stringer(key, any(v).(fmt.Stringer)) }
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return func(key string, v T) Field { return zap.Bool(key, as[bool](v)) }
+	case reflect.Int:
+		return func(key string, v T) Field { return zap.Int(key, as[int](v)) }
+	case reflect.Int8:
+		return func(key string, v T) Field { return zap.Int8(key, as[int8](v)) }
+	case reflect.Int16:
+		return func(key string, v T) Field { return zap.Int16(key, as[int16](v)) }
+	case reflect.Int32:
+		return func(key string, v T) Field { return zap.Int32(key, as[int32](v)) }
+	case reflect.Int64:
+		return func(key string, v T) Field { return zap.Int64(key, as[int64](v)) }
+	case reflect.Uint:
+		return func(key string, v T) Field { return zap.Uint(key, as[uint](v)) }
+	case reflect.Uint8:
+		return func(key string, v T) Field { return zap.Uint8(key, as[uint8](v)) }
+	case reflect.Uint16:
+		return func(key string, v T) Field { return zap.Uint16(key, as[uint16](v)) }
+	case reflect.Uint32:
+		return func(key string, v T) Field { return zap.Uint32(key, as[uint32](v)) }
+	case reflect.Uint64:
+		return func(key string, v T) Field { return zap.Uint64(key, as[uint64](v)) }
+	case reflect.Uintptr:
+		return func(key string, v T) Field { return zap.Uintptr(key, as[uintptr](v)) }
+	case reflect.Float32:
+		return func(key string, v T) Field { return zap.Float32(key, as[float32](v)) }
+	case reflect.Float64:
+		return func(key string, v T) Field { return zap.Float64(key, as[float64](v)) }
+	case reflect.Complex64:
+		return func(key string, v T) Field { return zap.Complex64(key, as[complex64](v)) }
+	case reflect.Complex128:
+		return func(key string, v T) Field { return zap.Complex128(key, as[complex128](v)) }
+	case reflect.String:
+		return func(key string, v T) Field { return zap.String(key, as[string](v)) }
+	default:
+		return func(key string, v T) Field { return zap.Any(key, v) }
+	}
+}
+
+func typeOf[T any]() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+
+// as converts v to type U which has the same underlying type with T.
+func as[U, T any](v T) U { return *(*U)(unsafe.Pointer(&v)) }
+
+func keyStringFuncOf[K comparable]() func(k K) string {
+	if typeOf[K]().Kind() == reflect.String {
+		return func(k K) string { return as[string](k) }
+	}
+	return func(k K) string { return fmt.Sprint(k) }
+}
+
+type sliceMarshaler[T any] struct {
+	vs []T
+	fn FieldFunc[T]
+}
+
+func (s sliceMarshaler[T]) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	var err error
+	for _, v := range s.vs {
+		err = multierr.Append(err, appendField(enc, s.fn("", v)))
+	}
+	return err
+}
+
+type mapMarshaler[K comparable, V any] struct {
+	m     map[K]V
+	keyFn func(K) string
+	fn    FieldFunc[V]
+}
+
+func (m mapMarshaler[K, V]) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	type entry struct {
+		key string
+		val V
+	}
+	entries := make([]entry, 0, len(m.m))
+	for k, v := range m.m {
+		entries = append(entries, entry{m.keyFn(k), v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	for _, e := range entries {
+		m.fn(e.key, e.val).AddTo(enc)
+	}
+	return nil
+}
+
+// appendField appends the value of the Field to the array encoder, the key is ignored.
+func appendField(enc zapcore.ArrayEncoder, f Field) error {
+	switch f.Type {
+	case zapcore.ArrayMarshalerType:
+		return enc.AppendArray(f.Interface.(zapcore.ArrayMarshaler))
+	case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		return enc.AppendObject(f.Interface.(zapcore.ObjectMarshaler))
+	case zapcore.BinaryType, zapcore.ReflectType:
+		return enc.AppendReflected(f.Interface)
+	case zapcore.BoolType:
+		enc.AppendBool(f.Integer == 1)
+	case zapcore.ByteStringType:
+		enc.AppendByteString(f.Interface.([]byte))
+	case zapcore.Complex128Type:
+		enc.AppendComplex128(f.Interface.(complex128))
+	case zapcore.Complex64Type:
+		enc.AppendComplex64(f.Interface.(complex64))
+	case zapcore.DurationType:
+		enc.AppendDuration(time.Duration(f.Integer))
+	case zapcore.Float64Type:
+		enc.AppendFloat64(math.Float64frombits(uint64(f.Integer)))
+	case zapcore.Float32Type:
+		enc.AppendFloat32(math.Float32frombits(uint32(f.Integer)))
+	case zapcore.Int64Type:
+		enc.AppendInt64(f.Integer)
+	case zapcore.Int32Type:
+		enc.AppendInt32(int32(f.Integer))
+	case zapcore.Int16Type:
+		enc.AppendInt16(int16(f.Integer))
+	case zapcore.Int8Type:
+		enc.AppendInt8(int8(f.Integer))
+	case zapcore.StringType:
+		enc.AppendString(f.String)
+	case zapcore.TimeType:
+		if f.Interface != nil {
+			enc.AppendTime(time.Unix(0, f.Integer).In(f.Interface.(*time.Location)))
+		} else {
+			enc.AppendTime(time.Unix(0, f.Integer))
+		}
+	case zapcore.TimeFullType:
+		enc.AppendTime(f.Interface.(time.Time))
+	case zapcore.Uint64Type:
+		enc.AppendUint64(uint64(f.Integer))
+	case zapcore.Uint32Type:
+		enc.AppendUint32(uint32(f.Integer))
+	case zapcore.Uint16Type:
+		enc.AppendUint16(uint16(f.Integer))
+	case zapcore.Uint8Type:
+		enc.AppendUint8(uint8(f.Integer))
+	case zapcore.UintptrType:
+		enc.AppendUintptr(uintptr(f.Integer))
+	case zapcore.StringerType:
+		enc.AppendString(fmt.Sprint(f.Interface))
+	case zapcore.ErrorType:
+		enc.AppendString(f.Interface.(error).Error())
+	case zapcore.SkipType:
+	default:
+		return fmt.Errorf("log: unsupported field type %v in array", f.Type)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package log_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/things-go/log"
+)
+
+type testStatus int
+
+type testPoint struct {
+	X, Y int
+}
+
+func init() {
+	log.RegisterEncoder(func(key string, v testPoint) log.Field {
+		return log.String(key, "("+strconv.Itoa(v.X)+","+strconv.Itoa(v.Y)+")")
+	})
+}
+
+func Test_Valuer_Generic(t *testing.T) {
+	l, buf := newBufferLogger()
+	l = l.WithValuer(
+		log.From("status", func(context.Context) testStatus { return 2 }),
+		log.From("name", func(context.Context) string { return "jack" }),
+		log.From("cost", func(context.Context) time.Duration { return time.Second }),
+		log.From("point", func(context.Context) testPoint { return testPoint{1, 2} }),
+		log.Slice("ids", func(context.Context) []int64 { return []int64{1, 2, 3} }),
+		log.Slice("points", func(context.Context) []testPoint { return []testPoint{{1, 2}, {3, 4}} }),
+		log.Map("labels", func(context.Context) map[string]int { return map[string]int{"b": 2, "a": 1} }),
+	)
+	l.Infox("generic")
+	got := buf.String()
+	for _, want := range []string{
+		`"status":2`,
+		`"name":"jack"`,
+		`"cost":"1s"`,
+		`"point":"(1,2)"`,
+		`"ids":[1,2,3]`,
+		`"points":["(1,2)","(3,4)"]`,
+		`"labels":{"a":1,"b":2}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want contains %s, got: %s", want, got)
+		}
+	}
+}
+
+type testAccount struct{ name string }
+
+func (u *testAccount) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.name)
+	return nil
+}
+
+func Test_Valuer_Generic_NilPointer(t *testing.T) {
+	l, buf := newBufferLogger()
+	l = l.WithValuer(
+		log.From("user", func(context.Context) *testAccount { return nil }),
+		log.From("owner", func(context.Context) *testAccount { return &testAccount{"jack"} }),
+		log.Slice("users", func(context.Context) []*testAccount { return []*testAccount{nil, {"rose"}} }),
+	)
+	l.Infox("nil")
+	got := buf.String()
+	for _, want := range []string{
+		`"user":null`,
+		`"owner":{"name":"jack"}`,
+		`"users":[null,{"name":"rose"}]`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want contains %s, got: %s", want, got)
+		}
+	}
+}
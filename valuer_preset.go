@@ -1,7 +1,15 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 // Package returns a Valuer that returns an immutable Valuer which key is pkg
@@ -36,3 +44,120 @@ func RequestId(f func(c context.Context) string) Valuer {
 func Source(f func(c context.Context) string) Valuer {
 	return FromString("source", f)
 }
+
+/**************************** runtime info Valuer ******************************************/
+
+// processStartTime the start time of the process, used by Uptime.
+var processStartTime = time.Now()
+
+// immutOmitEmpty returns an immutable Valuer, which returns Skip() if v is empty.
+func immutOmitEmpty(key, v string) Valuer {
+	if v == "" {
+		return wrapperField(zap.Skip())
+	}
+	return ImmutString(key, v)
+}
+
+// Hostname returns an immutable Valuer which key is hostname, computed once.
+func Hostname() Valuer {
+	name, _ := os.Hostname()
+	return immutOmitEmpty("hostname", name)
+}
+
+// Pid returns an immutable Valuer which key is pid, computed once.
+func Pid() Valuer {
+	return ImmutInt("pid", os.Getpid())
+}
+
+// GoVersion returns an immutable Valuer which key is goVersion, computed once.
+func GoVersion() Valuer {
+	return ImmutString("goVersion", runtime.Version())
+}
+
+// Version returns an immutable Valuer which key is version, computed once.
+// the value is the main module version from debug.ReadBuildInfo,
+// it is "(devel)" when build in module workspace.
+func Version() Valuer {
+	var version string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+	}
+	return immutOmitEmpty("version", version)
+}
+
+// VcsRevision returns an immutable Valuer which key is vcsRevision, computed once.
+// the value is the vcs.revision from debug.ReadBuildInfo,
+// a "-dirty" suffix will be added if vcs.modified is true.
+func VcsRevision() Valuer {
+	var revision string
+	var modified bool
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return immutOmitEmpty("vcsRevision", revision)
+}
+
+// PodName returns an immutable Valuer which key is podName, computed once.
+// the value is from environment POD_NAME, fallback to HOSTNAME.
+// it is skipped when not found.
+func PodName() Valuer {
+	name := os.Getenv("POD_NAME")
+	if name == "" {
+		name = os.Getenv("HOSTNAME")
+	}
+	return immutOmitEmpty("podName", name)
+}
+
+// PodNamespace returns an immutable Valuer which key is podNamespace, computed once.
+// the value is from environment POD_NAMESPACE, fallback to the service account namespace file.
+// it is skipped when not found.
+func PodNamespace() Valuer {
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		b, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+		if err == nil {
+			namespace = strings.TrimSpace(string(b))
+		}
+	}
+	return immutOmitEmpty("podNamespace", namespace)
+}
+
+// Goroutine returns a Valuer which key is goroutine, the id of current goroutine.
+func Goroutine() Valuer {
+	return func(context.Context) Field {
+		return zap.Uint64("goroutine", goroutineID())
+	}
+}
+
+// Uptime returns a Valuer which key is uptime, the duration since the process started.
+func Uptime() Valuer {
+	return func(context.Context) Field {
+		return zap.Duration("uptime", time.Since(processStartTime))
+	}
+}
+
+// goroutineID parse the goroutine id from the stack header, such as "goroutine 1 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
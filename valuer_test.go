@@ -0,0 +1,178 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/things-go/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newBufferLogger(opts ...log.Option) (*log.Log, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	opts = append([]log.Option{
+		log.WithLevel("debug"),
+		log.WithAdapter(log.AdapterCustom, buf),
+	}, opts...)
+	return log.NewLogger(opts...), buf
+}
+
+func Test_Valuer_Lazy(t *testing.T) {
+	called := 0
+	v := func(ctx context.Context) log.Field {
+		called++
+		return log.String("k", "v")
+	}
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(testNativeZapEncoderConfig),
+		zapcore.AddSync(&bytes.Buffer{}),
+		zapcore.ErrorLevel,
+	)
+	l := log.NewLoggerWith(zap.New(core), log.NewAtomicLevelAt(log.DebugLevel)).
+		WithValuer(v)
+	l.Infox("filtered by core")
+	l.Infow("filtered by core")
+	if called != 0 {
+		t.Errorf("Valuer should not be evaluated, but called %d", called)
+	}
+	l.Errorx("written")
+	if called != 1 {
+		t.Errorf("Valuer should be evaluated once, but called %d", called)
+	}
+}
+
+func Test_Valuer_Panic(t *testing.T) {
+	l, buf := newBufferLogger()
+	l = l.WithValuer(func(ctx context.Context) log.Field {
+		panic("oops")
+	})
+	l.Infox("valuer panic")
+	if got := buf.String(); !strings.Contains(got, `"valuer_error":"oops"`) {
+		t.Errorf("want valuer_error field, got: %s", got)
+	}
+}
+
+func Test_Valuer_Skip(t *testing.T) {
+	l, buf := newBufferLogger()
+	l = l.WithValuer(
+		func(ctx context.Context) log.Field { return log.Skip() },
+		log.OmitEmpty(log.ImmutString("empty", "")),
+		log.OmitEmpty(log.ImmutReflect("nil", nil)),
+		log.OmitEmpty(log.ImmutString("present", "yes")),
+	)
+	l.Infow("valuer skip")
+	got := buf.String()
+	if strings.Contains(got, `"empty"`) || strings.Contains(got, `"nil"`) {
+		t.Errorf("empty field should be skipped, got: %s", got)
+	}
+	if !strings.Contains(got, `"present":"yes"`) {
+		t.Errorf("want present field, got: %s", got)
+	}
+}
+
+func Test_Valuer_Combinator(t *testing.T) {
+	l, buf := newBufferLogger()
+	l = l.WithValuer(
+		log.OnLevel(log.ErrorLevel, log.ImmutString("onError", "yes")),
+		log.When(func(ctx context.Context) bool {
+			lvl, ok := log.LevelFromContext(ctx)
+			return ok && lvl == log.WarnLevel
+		}, log.ImmutString("onWarn", "yes")),
+		log.Group("group",
+			log.ImmutString("a", "1"),
+			func(ctx context.Context) log.Field { return log.Skip() },
+			log.ImmutInt("b", 2),
+		),
+		log.Group("empty", log.OnLevel(log.FatalLevel, log.ImmutString("c", "3"))),
+	)
+
+	l.Infox("info")
+	got := buf.String()
+	if strings.Contains(got, "onError") || strings.Contains(got, "onWarn") || strings.Contains(got, "empty") {
+		t.Errorf("level-gated fields should be skipped, got: %s", got)
+	}
+	if !strings.Contains(got, `"group":{"a":"1","b":2}`) {
+		t.Errorf("want group field, got: %s", got)
+	}
+
+	buf.Reset()
+	l.Warnx("warn")
+	if got := buf.String(); !strings.Contains(got, `"onWarn":"yes"`) || strings.Contains(got, "onError") {
+		t.Errorf("want onWarn field only, got: %s", got)
+	}
+
+	buf.Reset()
+	l.Errorx("error")
+	if got := buf.String(); !strings.Contains(got, `"onError":"yes"`) {
+		t.Errorf("want onError field, got: %s", got)
+	}
+}
+
+func Test_Valuer_HoldContext(t *testing.T) {
+	var ctxs []context.Context
+	l, _ := newBufferLogger()
+	l = l.WithValuer(func(ctx context.Context) log.Field {
+		ctxs = append(ctxs, ctx)
+		return log.Skip()
+	})
+	l.Infox("info")
+	l.Errorx("error")
+	for i, want := range []log.Level{log.InfoLevel, log.ErrorLevel} {
+		if lvl, ok := log.LevelFromContext(ctxs[i]); !ok || lvl != want {
+			t.Errorf("want the held context keeps level %v, got: %v", want, lvl)
+		}
+	}
+}
+
+func Test_Valuer_RuntimePreset(t *testing.T) {
+	t.Setenv("POD_NAME", "pod-0")
+	t.Setenv("POD_NAMESPACE", "default")
+
+	l, buf := newBufferLogger()
+	l = l.WithValuer(
+		log.Pid(),
+		log.GoVersion(),
+		log.Goroutine(),
+		log.Uptime(),
+		log.PodName(),
+		log.PodNamespace(),
+	)
+	l.Infox("runtime")
+	got := buf.String()
+	for _, want := range []string{
+		`"pid":`,
+		`"goVersion":"` + runtime.Version() + `"`,
+		`"goroutine":`,
+		`"uptime":`,
+		`"podName":"pod-0"`,
+		`"podNamespace":"default"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want contains %s, got: %s", want, got)
+		}
+	}
+	if strings.Contains(got, `"goroutine":0`) {
+		t.Errorf("goroutine id should not be zero, got: %s", got)
+	}
+}
+
+func Test_Valuer_Caller(t *testing.T) {
+	l, buf := newBufferLogger()
+	l = l.WithValuer(log.Caller(1))
+	_, _, line, _ := runtime.Caller(0)
+	l.Info("print")
+	l.Infof("printf")
+	l.Infow("sweeten")
+	l.Infox("structured")
+	for i, s := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		want := `"caller":"valuer_test.go:` + strconv.Itoa(line+1+i) + `"`
+		if !strings.Contains(s, want) {
+			t.Errorf("want %s, got: %s", want, s)
+		}
+	}
+}
@@ -35,12 +35,28 @@ const (
 	AdapterConsoleCustom = "console-custom" // console and custom io.Writer
 	AdapterFileCustom    = "file-custom"    // file and custom io.Writer
 	AdapterMultiCustom   = "multi-custom"   // file, console and custom io.Writer
+	AdapterSyslog        = "syslog"         // syslog, see SyslogConfig
+	AdapterJournald      = "journald"       // systemd-journald, see JournaldConfig
+	AdapterNet           = "net"            // tcp, udp or unix socket, see NetConfig
+	AdapterLoki          = "loki"           // grafana loki push api, see ShipperConfig
+	AdapterElasticsearch = "elasticsearch"  // elasticsearch bulk api, see ShipperConfig
+	AdapterForward       = "forward"        // fluentd/fluent-bit forward protocol, see ForwardConfig
 )
 
 // format defined
 const (
 	FormatJson    = "json"
 	FormatConsole = "console"
+	FormatLogfmt  = "logfmt"
+	FormatPretty  = "pretty" // for development, human-friendly
+)
+
+// duplicate key policy defined
+const (
+	DuplicateKeyKeepLast  = "keep-last"  // keep the last field, such as call-site fields override Valuer and With fields
+	DuplicateKeyKeepFirst = "keep-first" // keep the first field
+	DuplicateKeyRename    = "rename"     // rename the duplicate key with suffix "_<n>", such as key_1
+	DuplicateKeyError     = "error"      // keep the first field, and report an error to zap ErrorOutput
 )
 
 // encode level defined
@@ -57,6 +73,7 @@ func New(opts ...Option) (*zap.Logger, zap.AtomicLevel) {
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.closers = &closers{}
 	var options []zap.Option
 
 	if c.AddCaller {
@@ -73,12 +90,52 @@ func New(opts ...Option) (*zap.Logger, zap.AtomicLevel) {
 		level = zap.NewAtomicLevelAt(zap.InfoLevel)
 	}
 
-	// 初始化core
-	core := zapcore.NewCore(
-		toEncoder(c, level), // 设置encoder
-		toWriter(c),         // 设置输出
-		level,               // 设置日志输出等级
-	)
+	p := toProfile(c.Profile)
+	if p != nil && c.EncoderConfig == nil {
+		encoderConfig := p.encoderConfig
+		c.EncoderConfig = &encoderConfig
+	}
+
+	var core zapcore.Core
+	enc := newMetricsEncoder(toEncoder(c, level), c.Metrics)
+	switch adapter := strings.ToLower(c.Adapter); adapter {
+	case AdapterLoki, AdapterElasticsearch:
+		// 批量推送
+		s := newShipper(adapter, c.Shipper, toMetrics(c), newSinkRescue(adapter, c))
+		c.closers.add(s)
+		core = newShipperCore(enc, level, s)
+	case AdapterForward:
+		// fluent forward 协议
+		f := newForwarder(c.Forward, toMetrics(c), newSinkRescue(SinkForward, c))
+		c.closers.add(f)
+		var text zapcore.Encoder
+		if len(c.Fallback) > 0 {
+			// the fallback writes the entries with Format instead of msgpack, without color.
+			tc := *c
+			tc.Adapter = AdapterCustom
+			text = toEncoder(&tc, level)
+		}
+		core = newForwardCore(enc, text, level, f)
+	case AdapterFile, AdapterMulti, AdapterFileCustom, AdapterMultiCustom:
+		if g := newDiskGuard(c); g != nil {
+			// 文件输出磁盘使用保护
+			core = g.newCore(c, enc, level)
+			break
+		}
+		fallthrough
+	default:
+		// 初始化core
+		core = zapcore.NewCore(
+			enc,         // 设置encoder
+			toWriter(c), // 设置输出
+			level,       // 设置日志输出等级
+		)
+	}
+	core = newMetricsCore(core, c.Metrics)         // 日志管道指标
+	core = newRecorderCore(core, c.FlightRecorder) // 飞行记录器
+	core = newDedupCore(core, c.DuplicateKey)      // 重复key处理
+	core = newProfileCore(core, p)                 // 日志格式规范
+	core = newCloseCore(core, c.closers)           // 关闭适配器
 	return zap.New(core, options...), level
 }
 
@@ -104,10 +161,30 @@ func toEncoder(c *Config, level zap.AtomicLevel) zapcore.Encoder {
 		}
 	}
 
-	if c.Format == "console" {
+	switch strings.ToLower(c.Adapter) {
+	case AdapterSyslog:
+		return NewSyslogEncoder(*encoderConfig, c.Syslog)
+	case AdapterJournald:
+		return NewJournaldEncoder(*encoderConfig, c.Journald)
+	case AdapterForward:
+		return NewForwardEncoder(*encoderConfig)
+	case AdapterElasticsearch:
+		// bulk api only accept json document
+		return zapcore.NewJSONEncoder(*encoderConfig)
+	}
+	switch c.Format {
+	case FormatConsole:
 		return zapcore.NewConsoleEncoder(*encoderConfig)
+	case FormatLogfmt:
+		return NewLogfmtEncoder(*encoderConfig)
+	case FormatPretty:
+		// color only for stdout terminal
+		adapter := strings.ToLower(c.Adapter)
+		color := (adapter == "" || adapter == AdapterConsole) && ColorEnabled()
+		return NewPrettyEncoder(*encoderConfig, color)
+	default: // json
+		return zapcore.NewJSONEncoder(*encoderConfig)
 	}
-	return zapcore.NewJSONEncoder(*encoderConfig)
 }
 
 func toEncodeLevel(l string) zapcore.LevelEncoder {
@@ -125,25 +202,37 @@ func toEncodeLevel(l string) zapcore.LevelEncoder {
 	}
 }
 
+func toFileWriter(c *Config) zapcore.WriteSyncer {
+	lj := &lumberjack.Logger{ // 文件切割
+		Filename:   filepath.Join(c.Path, c.Filename),
+		MaxSize:    c.MaxSize,
+		MaxAge:     c.MaxAge,
+		MaxBackups: c.MaxBackups,
+		LocalTime:  c.LocalTime,
+		Compress:   c.Compress,
+	}
+	if hooks := toRotateHooks(c); len(hooks) > 0 || c.Metrics != nil {
+		// 文件切割后处理及切割统计, 仅配置切割后处理时启动后台任务
+		w := newRotateWriter(lj, hooks, c.ErrorHandler, toMetrics(c))
+		c.closers.add(w)
+		return newSinkWriter(SinkFile, zapcore.AddSync(w), c)
+	}
+	c.closers.add(lj)
+	return newSinkWriter(SinkFile, zapcore.AddSync(lj), c)
+}
+
 func toWriter(c *Config) zapcore.WriteSyncer {
 	fileWriter := func() zapcore.WriteSyncer {
-		return zapcore.AddSync(&lumberjack.Logger{ // 文件切割
-			Filename:   filepath.Join(c.Path, c.Filename),
-			MaxSize:    c.MaxSize,
-			MaxAge:     c.MaxAge,
-			MaxBackups: c.MaxBackups,
-			LocalTime:  c.LocalTime,
-			Compress:   c.Compress,
-		})
+		return toFileWriter(c)
 	}
 	stdoutWriter := func() zapcore.WriteSyncer {
-		return zapcore.AddSync(os.Stdout)
+		return newSinkWriter(SinkConsole, zapcore.AddSync(os.Stdout), c)
 	}
 	customWriter := func(w ...zapcore.WriteSyncer) []zapcore.WriteSyncer {
 		ws := make([]zapcore.WriteSyncer, 0, len(c.Writer)+len(w))
 
-		for _, writer := range c.Writer {
-			ws = append(ws, zapcore.AddSync(writer))
+		for i, writer := range c.Writer {
+			ws = append(ws, newSinkWriter(customSinkName(i, len(c.Writer)), zapcore.AddSync(writer), c))
 		}
 		for _, writer := range w {
 			ws = append(ws, zapcore.AddSync(writer))
@@ -170,6 +259,18 @@ func toWriter(c *Config) zapcore.WriteSyncer {
 		return zapcore.NewMultiWriteSyncer(customWriter(stdoutWriter())...)
 	case "multi-custom":
 		return zapcore.NewMultiWriteSyncer(customWriter(stdoutWriter(), fileWriter())...)
+	case "syslog":
+		w := newSyslogWriter(c.Syslog)
+		c.closers.add(w)
+		return newSinkWriter(SinkSyslog, w, c)
+	case "journald":
+		w := newJournaldWriter(c.Journald)
+		c.closers.add(w)
+		return newSinkWriter(SinkJournald, w, c)
+	case "net":
+		w := newNetWriter(c.Net, toMetrics(c))
+		c.closers.add(w)
+		return newSinkWriter(SinkNet, w, c)
 	default: // console
 		return stdoutWriter()
 	}